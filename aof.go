@@ -0,0 +1,329 @@
+package mcache
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+// Append log operations
+const (
+	opSet byte = iota + 1
+	opDelete
+	opRefresh
+)
+
+// AppendLog wraps a cache, appending every Set, Delete, and Refresh operation to a log,
+// which can be replayed with ReplayLog to restore the cache after restart.
+// The log uses the same encoding of keys and values as WriteSnapshot.
+type AppendLog[K comparable, V any] struct {
+	cache  *Cache[K, V]
+	w      io.Writer
+	err    error
+	record []byte
+	m      sync.Mutex
+	cm     sync.Mutex    // Guards the background compaction
+	stop   chan struct{} // Closed to stop the background compaction
+	done   chan struct{} // Closed when the background compaction stops
+}
+
+// LogSink is the destination of the log compacted in the background with CompactEvery.
+// Rotate is called with the function compacting the log into a writer, which the log is then continued in,
+// so the writer must stay open. The old log can be discarded once compact returns without an error.
+// The sink is responsible for handling the errors.
+type LogSink interface {
+	Rotate(compact func(io.Writer) error) error
+}
+
+// NewAppendLog creates a cache wrapper writing operations to w.
+func NewAppendLog[K comparable, V any](c *Cache[K, V], w io.Writer) *AppendLog[K, V] {
+	return &AppendLog[K, V]{
+		cache: c,
+		w:     w,
+	}
+}
+
+// Get returns value from the cache, it is not logged.
+func (l *AppendLog[K, V]) Get(key K) (V, bool) {
+	return l.cache.Get(key)
+}
+
+// Set adds or replaces a value with key and given TTL, and logs the value and the expiration time the cache
+// has actually stored, so TTL limits, jitter, and value size handling are applied once and replayed as is.
+func (l *AppendLog[K, V]) Set(key K, value V, ttl time.Duration) {
+	l.m.Lock()
+
+	l.cache.Set(key, value, ttl)
+
+	if stored, expires, ok := l.cache.Peek(key); ok {
+		l.append(opSet, key, expires, &stored)
+	} else {
+		// The value was rejected, and the old one is gone
+		l.append(opDelete, key, time.Time{}, nil)
+	}
+
+	l.m.Unlock()
+}
+
+// Delete deletes the key from the cache, and logs the operation if the key was present.
+func (l *AppendLog[K, V]) Delete(key K) bool {
+	l.m.Lock()
+
+	ok := l.cache.Delete(key)
+	if ok {
+		l.append(opDelete, key, time.Time{}, nil)
+	}
+
+	l.m.Unlock()
+
+	return ok
+}
+
+// Refresh sets new TTL for the key, and logs the operation with the assigned expiration time if the key was present.
+func (l *AppendLog[K, V]) Refresh(key K, ttl time.Duration) bool {
+	l.m.Lock()
+
+	ok := l.cache.Refresh(key, ttl)
+	if ok {
+		_, expires, _ := l.cache.Peek(key)
+		l.append(opRefresh, key, expires, nil)
+	}
+
+	l.m.Unlock()
+
+	return ok
+}
+
+// Len returns number of items in the cache.
+func (l *AppendLog[K, V]) Len() int {
+	return l.cache.Len()
+}
+
+// Err returns the first error encountered while writing the log. Operations are not logged after an error,
+// until the log is compacted into a new writer.
+func (l *AppendLog[K, V]) Err() error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	return l.err
+}
+
+// Compact writes the current cache contents to w as a fresh log, and continues logging to w.
+// The old log can be discarded once Compact returns without an error. Operations are blocked while compacting.
+func (l *AppendLog[K, V]) Compact(w io.Writer) error {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	if err := l.cache.stream(func(entry snapshotEntry[K, V]) (err error) {
+		if l.record, err = encodeRecord(l.record[:0], opSet, entry.Key, entry.Expires, &entry.Value); err != nil {
+			return err
+		}
+
+		_, err = bw.Write(l.record)
+
+		return err
+	}); err != nil {
+		return err
+	}
+
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+
+	l.w = w
+	l.err = nil
+
+	return nil
+}
+
+// CompactEvery compacts the log into the sink every interval of the cache clock, until Close is called.
+// It replaces the compaction started before, non-positive interval only stops it.
+func (l *AppendLog[K, V]) CompactEvery(interval time.Duration, sink LogSink) {
+	l.Close()
+
+	if interval <= 0 {
+		return
+	}
+
+	l.cm.Lock()
+
+	l.stop, l.done = make(chan struct{}), make(chan struct{})
+
+	go l.compactor(interval, sink, l.stop, l.done)
+
+	l.cm.Unlock()
+}
+
+// Close stops the background compaction started with CompactEvery.
+func (l *AppendLog[K, V]) Close() {
+	l.cm.Lock()
+	defer l.cm.Unlock()
+
+	if l.stop != nil {
+		close(l.stop)
+		<-l.done
+
+		l.stop, l.done = nil, nil
+	}
+}
+
+func (l *AppendLog[K, V]) compactor(interval time.Duration, sink LogSink, stop, done chan struct{}) {
+	defer close(done)
+
+	t := newClockTicker(l.cache.opts.clock, interval)
+	defer t.stop()
+
+	for {
+		select {
+		case <-t.C:
+			_ = sink.Rotate(l.Compact)
+
+			t.next()
+		case <-stop:
+			return
+		}
+	}
+}
+
+// append writes the operation to the log, unless writing failed before.
+func (l *AppendLog[K, V]) append(op byte, key K, expires time.Time, value *V) {
+	if l.err != nil {
+		return
+	}
+
+	l.record, l.err = encodeRecord(l.record[:0], op, key, expires, value)
+
+	if l.err == nil {
+		_, l.err = l.w.Write(l.record)
+	}
+}
+
+// encodeRecord appends the length-prefixed log record to buf.
+func encodeRecord[K comparable, V any](buf []byte, op byte, key K, expires time.Time, value *V) ([]byte, error) {
+	payload := []byte{op}
+
+	if op != opDelete {
+		payload = binary.AppendVarint(payload, expires.UnixNano())
+	}
+
+	k, err := encodeValue(key)
+	if err != nil {
+		return buf, err
+	}
+
+	payload = binary.AppendUvarint(payload, uint64(len(k)))
+	payload = append(payload, k...)
+
+	if value != nil {
+		v, err := encodeValue(*value)
+		if err != nil {
+			return buf, err
+		}
+
+		payload = binary.AppendUvarint(payload, uint64(len(v)))
+		payload = append(payload, v...)
+	}
+
+	buf = binary.AppendUvarint(buf, uint64(len(payload)))
+
+	return append(buf, payload...), nil
+}
+
+// ReplayLog applies operations read from the log written by AppendLog. Items that have already expired are skipped.
+// Operations read before a malformed or truncated record are applied, and ErrBadSnapshot is returned.
+func (c *Cache[K, V]) ReplayLog(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	for {
+		if _, err := br.Peek(1); errors.Is(err, io.EOF) {
+			return nil
+		}
+
+		record, err := readBytes(br)
+		if err != nil {
+			return err
+		}
+
+		if err = c.replay(record); err != nil {
+			return err
+		}
+	}
+}
+
+// replay applies a single log record.
+func (c *Cache[K, V]) replay(record []byte) error {
+	if len(record) == 0 {
+		return ErrBadSnapshot
+	}
+
+	op, record := record[0], record[1:]
+
+	var expires time.Time
+
+	if op == opSet || op == opRefresh {
+		nanos, n := binary.Varint(record)
+		if n <= 0 {
+			return ErrBadSnapshot
+		}
+
+		expires, record = time.Unix(0, nanos), record[n:]
+	}
+
+	data, record, err := nextField(record)
+	if err != nil {
+		return err
+	}
+
+	var key K
+
+	if err = decodeValue(data, &key); err != nil {
+		return err
+	}
+
+	switch op {
+	case opSet:
+		if data, _, err = nextField(record); err != nil {
+			return err
+		}
+
+		var value V
+
+		if err = decodeValue(data, &value); err != nil {
+			return err
+		}
+
+		if c.now().Before(expires) {
+			c.SetUntil(key, value, expires)
+		} else {
+			c.Delete(key)
+		}
+	case opDelete:
+		c.Delete(key)
+	case opRefresh:
+		if c.now().Before(expires) {
+			c.expireAt(key, expires)
+		} else {
+			c.Delete(key)
+		}
+	default:
+		return ErrBadSnapshot
+	}
+
+	return nil
+}
+
+// expireAt sets the logged expiration time of the key, without applying TTL limits again.
+func (c *Cache[K, V]) expireAt(key K, expires time.Time) {
+	c.m.Lock()
+
+	if v, ok := c.cache[key]; ok && c.alive(v.Ptr) {
+		c.reschedule(v.Ptr, c.deadline(expires, v.Ptr.Relaxed))
+	}
+
+	c.m.Unlock()
+}
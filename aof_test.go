@@ -0,0 +1,185 @@
+package mcache_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/clockmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) { return 0, errors.New("disk full") }
+
+// bufferSink compacts logs into new buffers
+type bufferSink struct {
+	logs []*bytes.Buffer
+	m    sync.Mutex
+}
+
+func (s *bufferSink) Rotate(compact func(io.Writer) error) error {
+	var log bytes.Buffer
+
+	if err := compact(&log); err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	s.logs = append(s.logs, &log)
+	s.m.Unlock()
+
+	return nil
+}
+
+func (s *bufferSink) count() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return len(s.logs)
+}
+
+func TestAppendLog(t *testing.T) {
+	var log bytes.Buffer
+
+	c := mcache.New[string, int]()
+	l := mcache.NewAppendLog(c, &log)
+
+	l.Set("a", 1, 30*time.Millisecond)
+	l.Set("b", 2, 30*time.Millisecond)
+	l.Set("c", 3, 30*time.Millisecond)
+	require.True(t, l.Delete("b"))
+	require.False(t, l.Delete("b"))
+	require.True(t, l.Refresh("c", 50*time.Millisecond))
+	require.NoError(t, l.Err())
+
+	r := mcache.New[string, int]()
+
+	require.NoError(t, r.ReplayLog(bytes.NewReader(log.Bytes())))
+	require.Equal(t, []string{"a", "c"}, r.Keys())
+	require.Equal(t, []int{1, 3}, r.Values())
+
+	truncated := mcache.New[string, int]()
+
+	require.ErrorIs(t, truncated.ReplayLog(bytes.NewReader(log.Bytes()[:log.Len()-1])), mcache.ErrBadSnapshot)
+	require.Equal(t, 2, truncated.Len())
+
+	var compacted bytes.Buffer
+
+	require.NoError(t, l.Compact(&compacted))
+
+	l.Set("d", 4, 40*time.Millisecond)
+
+	s := mcache.New[string, int]()
+
+	require.NoError(t, s.ReplayLog(&compacted))
+	require.Equal(t, []string{"a", "d", "c"}, s.Keys())
+
+	require.Error(t, l.Compact(failingWriter{}))
+	require.NoError(t, l.Err())
+
+	f := mcache.NewAppendLog(mcache.New[string, int](), failingWriter{})
+
+	f.Set("e", 5, 10*time.Millisecond)
+	require.Error(t, f.Err())
+	require.Equal(t, 1, f.Len())
+
+	assert.Eventually(t, func() bool {
+		return 0 == l.Len()+r.Len()+s.Len()+truncated.Len()+f.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestAppendLogCompactEvery(t *testing.T) {
+	var log bytes.Buffer
+
+	c := mcache.New[string, int]()
+	l := mcache.NewAppendLog(c, &log)
+
+	l.Set("a", 1, time.Minute)
+	l.Set("a", 2, time.Minute)
+	l.Set("b", 3, time.Minute)
+
+	var sink bufferSink
+
+	l.CompactEvery(5*time.Millisecond, &sink)
+
+	assert.Eventually(t, func() bool {
+		return sink.count() >= 2
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	l.Close()
+	l.Close()
+
+	compacted := sink.count()
+
+	l.Set("c", 4, time.Minute)
+
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, compacted, sink.count())
+
+	r := mcache.New[string, int]()
+
+	require.NoError(t, r.ReplayLog(bytes.NewReader(sink.logs[compacted-1].Bytes())))
+	require.ElementsMatch(t, []string{"a", "b", "c"}, r.Keys())
+	require.ElementsMatch(t, []int{2, 3, 4}, r.Values())
+	require.NoError(t, l.Err())
+
+	l.CompactEvery(0, &sink)
+	l.Close()
+}
+
+func TestAppendLogTTLLimits(t *testing.T) {
+	var log bytes.Buffer
+
+	c := mcache.New[string, int](mcache.WithTTLLimits(0, time.Minute))
+	l := mcache.NewAppendLog(c, &log)
+
+	l.Set("a", 1, time.Hour)
+	l.Set("b", 2, time.Second)
+	require.True(t, l.Refresh("b", time.Hour))
+
+	_, want, _ := c.GetWithExpiry("a")
+	require.WithinDuration(t, time.Now().Add(time.Minute), want, time.Second)
+
+	r := mcache.New[string, int]()
+
+	require.NoError(t, r.ReplayLog(bytes.NewReader(log.Bytes())))
+
+	for _, key := range []string{"a", "b"} {
+		_, want, _ := c.GetWithExpiry(key)
+		_, got, ok := r.GetWithExpiry(key)
+		require.True(t, ok)
+		require.True(t, want.Equal(got), key)
+	}
+}
+
+func TestAppendLogCompactEveryClock(t *testing.T) {
+	clock := clockmock.New(time.Now())
+
+	var log bytes.Buffer
+
+	l := mcache.NewAppendLog(mcache.New[string, int](mcache.WithClock(clock)), &log)
+
+	l.Set("a", 1, time.Hour)
+
+	var sink bufferSink
+
+	l.CompactEvery(time.Minute, &sink)
+
+	time.Sleep(10 * time.Millisecond)
+	require.Zero(t, sink.count())
+
+	clock.Advance(time.Minute)
+
+	assert.Eventually(t, func() bool {
+		return sink.count() == 1
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	l.Close()
+}
@@ -0,0 +1,330 @@
+package mcache
+
+import (
+	"sort"
+	"time"
+)
+
+// Entry is a value with its own TTL for batch operations.
+type Entry[V any] struct {
+	Value V
+	TTL   time.Duration
+}
+
+// SetMany adds or replaces multiple values with the same TTL.
+// The lock is acquired once and the values are spliced into the expiration queue in a single pass.
+func (c *Cache[K, V]) SetMany(entries map[K]V, ttl time.Duration) {
+	expires := c.now().Add(ttl)
+
+	c.m.Lock()
+
+	head := c.head
+
+	items := make([]*item[K], 0, len(entries))
+
+	for key, value := range entries {
+		if i := c.addMany(key, value, expires, false); i != nil {
+			items = append(items, i)
+		}
+	}
+
+	c.spliceMany(items)
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.unlock()
+}
+
+// SetManyWithTTL works as SetMany, but each value has its own TTL.
+func (c *Cache[K, V]) SetManyWithTTL(entries map[K]Entry[V]) {
+	now := c.now()
+
+	c.m.Lock()
+
+	head := c.head
+
+	items := make([]*item[K], 0, len(entries))
+
+	for key, entry := range entries {
+		if i := c.addMany(key, entry.Value, now.Add(entry.TTL), false); i != nil {
+			items = append(items, i)
+		}
+	}
+
+	c.spliceMany(items)
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.unlock()
+}
+
+// Merge imports items from the other cache, keeping their expiration times.
+// When a key is present in both caches, conflict is called with both values to pick the value to store.
+// If conflict is nil, values from the other cache win. Values are checked against WithMaxValueSize as Set does.
+func (c *Cache[K, V]) Merge(other *Cache[K, V], conflict func(dst, src V) V) {
+	other.m.RLock()
+
+	src := other.derive()
+
+	for n := other.head; n != nil; n = n.Next {
+		if other.alive(n) {
+			src.append(n, other.cache[n.Key].Value)
+		}
+	}
+
+	other.m.RUnlock()
+
+	c.m.Lock()
+
+	head := c.head
+	items := make([]*item[K], 0, len(src.cache))
+
+	for n := src.head; n != nil; n = n.Next {
+		value := src.cache[n.Key].Value
+
+		v, exists := c.cache[n.Key]
+		if exists {
+			if conflict != nil && c.alive(v.Ptr) {
+				value = conflict(v.Value, value)
+			}
+
+			c.delete(n.Key)
+		}
+
+		if c.disabled.Load() {
+			continue
+		}
+
+		value, admitted := c.admit(n.Key, value)
+		if !admitted {
+			continue
+		}
+
+		i := &item[K]{
+			Key:      n.Key,
+			Expires:  n.Expires,
+			TTL:      n.TTL,
+			Relaxed:  n.Relaxed,
+			Info:     n.Info, // The source items are private copies
+			Priority: n.Priority,
+			Cost:     n.Cost,
+		}
+
+		c.store(n.Key, valuePtr[K, V]{
+			Value: value,
+			Ptr:   i,
+		})
+
+		if exists {
+			c.publish(EventUpdate, n.Key, value)
+		} else {
+			c.publish(EventSet, n.Key, value)
+		}
+
+		items = append(items, i)
+	}
+
+	c.spliceMany(items)
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.unlock()
+}
+
+// addMany stores the value in the map, returning its queue item to be spliced later.
+// Absolute expiration times are kept as given, see newItem. Returns nil if the cache is disabled.
+func (c *Cache[K, V]) addMany(key K, value V, expires time.Time, absolute bool) *item[K] {
+	replaced, ok := c.cache[key]
+	if ok {
+		// We are replacing the item
+		c.delete(key)
+	}
+
+	if c.disabled.Load() {
+		return nil
+	}
+
+	value, admitted := c.admit(key, value)
+	if !admitted {
+		return nil
+	}
+
+	i := c.newItem(key, expires, c.opts.precision == Relaxed, absolute)
+
+	c.track(i, replaced.Ptr)
+
+	c.store(key, valuePtr[K, V]{
+		Value: value,
+		Ptr:   i,
+	})
+
+	c.stats.sets.Add(1)
+
+	if ok {
+		c.publish(EventUpdate, key, value)
+	} else {
+		c.publish(EventSet, key, value)
+	}
+
+	return i
+}
+
+// spliceMany merges items into the queue in a single pass. The caller is responsible for re-arming the timer.
+func (c *Cache[K, V]) spliceMany(items []*item[K]) {
+	if len(items) == 0 {
+		return
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Expires.Before(items[j].Expires)
+	})
+
+	n := c.head
+
+	for _, i := range items {
+		for n != nil && !i.Expires.Before(n.Expires) {
+			n = n.Next
+		}
+
+		switch {
+		case n != nil:
+			c.insertBefore(i, n)
+		case c.tail != nil:
+			c.insertAfter(i, c.tail)
+		default:
+			c.head, c.tail = i, i
+		}
+	}
+}
+
+// DeleteMany removes multiple keys at once, returning the number of actually deleted keys.
+func (c *Cache[K, V]) DeleteMany(keys ...K) (deleted int) {
+	c.m.Lock()
+
+	head := c.head
+
+	for _, key := range keys {
+		c.publishRemoval(EventDelete, key)
+
+		if c.delete(key) {
+			deleted++
+		}
+	}
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.m.Unlock()
+
+	c.stats.deletes.Add(uint64(deleted))
+
+	return
+}
+
+// GetAndDeleteMany deletes multiple keys at once, returning values of the deleted keys.
+// As all keys are taken under a single lock, concurrent calls never get the same key.
+func (c *Cache[K, V]) GetAndDeleteMany(keys ...K) map[K]V {
+	values := make(map[K]V, len(keys))
+
+	c.m.Lock()
+
+	head := c.head
+
+	for _, key := range keys {
+		v, ok := c.cache[key]
+		if !ok || !c.alive(v.Ptr) {
+			continue
+		}
+
+		c.publish(EventDelete, key, v.Value)
+		c.delete(key)
+
+		values[key] = v.Value
+	}
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.m.Unlock()
+
+	c.stats.deletes.Add(uint64(len(values)))
+
+	return values
+}
+
+// RefreshMany sets new TTL for multiple keys at once, returning the number of refreshed keys.
+func (c *Cache[K, V]) RefreshMany(ttl time.Duration, keys ...K) int {
+	expires := c.now().Add(ttl)
+
+	c.m.Lock()
+
+	head := c.head
+	items := make([]*item[K], 0, len(keys))
+
+	for _, key := range keys {
+		v, ok := c.cache[key]
+		if !ok || !c.alive(v.Ptr) {
+			continue
+		}
+
+		// Skip duplicate keys, the item was already taken out of the queue
+		if v.Ptr.Prev == nil && v.Ptr.Next == nil && c.head != v.Ptr {
+			continue
+		}
+
+		c.remove(v.Ptr)
+		v.Ptr.Expires = c.deadline(c.clamp(expires), v.Ptr.Relaxed)
+		c.index(key)
+		items = append(items, v.Ptr)
+	}
+
+	c.spliceMany(items)
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.m.Unlock()
+
+	return len(items)
+}
+
+// DeleteWhere removes all items for which fn returns true, returning the number of deleted items.
+// The cache is locked while fn is executed, so fn must not call the cache methods.
+func (c *Cache[K, V]) DeleteWhere(fn func(K, V) bool) (deleted int) {
+	c.m.Lock()
+
+	head := c.head
+
+	for n := c.head; n != nil; {
+		next := n.Next
+
+		if fn(n.Key, c.cache[n.Key].Value) {
+			c.publishRemoval(EventDelete, n.Key)
+
+			if c.delete(n.Key) {
+				deleted++
+			}
+		}
+
+		n = next
+	}
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.m.Unlock()
+
+	c.stats.deletes.Add(uint64(deleted))
+
+	return
+}
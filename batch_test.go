@@ -0,0 +1,175 @@
+package mcache_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetMany(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 0, 30*time.Millisecond)
+	c.Set(5, 5, 80*time.Millisecond)
+
+	c.SetMany(map[int]int{1: 1, 2: 2, 3: 3}, 50*time.Millisecond)
+	c.SetManyWithTTL(map[int]mcache.Entry[int]{
+		4: {Value: 4, TTL: 10 * time.Millisecond},
+		6: {Value: 6, TTL: 100 * time.Millisecond},
+	})
+
+	require.Equal(t, 6, c.Len())
+
+	if v, ok := c.Get(1); assert.True(t, ok) {
+		assert.Equal(t, 1, v)
+	}
+
+	var seen []int
+
+	c.Range(func(k, _ int) bool {
+		seen = append(seen, k)
+		return true
+	})
+
+	require.Equal(t, 4, seen[0])
+	require.Equal(t, []int{5, 6}, seen[4:])
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestDeleteMany(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.SetMany(map[int]int{1: 1, 2: 2, 3: 3, 4: 4}, 50*time.Millisecond)
+	c.Set(0, 0, 10*time.Millisecond)
+
+	require.Equal(t, 3, c.DeleteMany(0, 1, 3, 5))
+	require.Equal(t, 2, c.Len())
+
+	_, ok := c.Get(1)
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestGetAndDeleteMany(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.SetMany(map[int]int{1: 10, 2: 20, 3: 30, 4: 40}, 50*time.Millisecond)
+
+	require.Equal(t, map[int]int{1: 10, 3: 30}, c.GetAndDeleteMany(1, 3, 5, 3))
+	require.Empty(t, c.GetAndDeleteMany(1, 3))
+	require.ElementsMatch(t, []int{2, 4}, c.Keys())
+	require.EqualValues(t, 2, c.Stats().Deletes)
+
+	// Concurrent claims never get the same key
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, 50*time.Millisecond)
+	}
+
+	keys := make([]int, 100)
+	for i := range keys {
+		keys[i] = i
+	}
+
+	var (
+		wg      sync.WaitGroup
+		claimed atomic.Int32
+	)
+
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			claimed.Add(int32(len(c.GetAndDeleteMany(keys...))))
+		}()
+	}
+
+	wg.Wait()
+
+	require.EqualValues(t, 100, claimed.Load())
+	require.Zero(t, c.Len())
+}
+
+func TestRefreshMany(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.SetMany(map[int]int{1: 1, 2: 2, 3: 3}, 20*time.Millisecond)
+
+	require.Equal(t, 2, c.RefreshMany(100*time.Millisecond, 1, 3, 5, 3))
+
+	assert.Eventually(t, func() bool {
+		return 2 == c.Len()
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	_, ok := c.Get(2)
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestDeleteWhere(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	c.SetMany(map[int]string{1: "tenant-a", 2: "tenant-b", 3: "tenant-a", 4: "tenant-c"}, 50*time.Millisecond)
+
+	require.Equal(t, 2, c.DeleteWhere(func(_ int, v string) bool {
+		return v == "tenant-a"
+	}))
+
+	require.ElementsMatch(t, []int{2, 4}, c.Keys())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestMerge(t *testing.T) {
+	dst := mcache.New[int, int]()
+	src := mcache.New[int, int]()
+
+	dst.Set(1, 1, 30*time.Millisecond)
+	dst.Set(2, 2, 30*time.Millisecond)
+	src.Set(2, 20, 40*time.Millisecond)
+	src.Set(3, 30, 50*time.Millisecond)
+
+	dst.Merge(src, func(a, b int) int { return a + b })
+
+	require.Equal(t, []int{1, 2, 3}, dst.Keys())
+	require.Equal(t, []int{1, 22, 30}, dst.Values())
+
+	_, expected, _ := src.GetWithExpiry(2)
+	_, expires, _ := dst.GetWithExpiry(2)
+	require.Equal(t, expected, expires)
+
+	assert.Eventually(t, func() bool {
+		return 0 == dst.Len()+src.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestMergeMaxValueSize(t *testing.T) {
+	dst := mcache.New[string, string](mcache.WithMaxValueSize[string](3, nil))
+	src := mcache.New[string, string]()
+
+	dst.Set("a", "old", time.Minute)
+	src.Set("a", "too long", time.Minute)
+	src.Set("b", "ok", time.Minute)
+
+	dst.Merge(src, nil)
+
+	require.Equal(t, []string{"b"}, dst.Keys())
+	require.EqualValues(t, 1, dst.Stats().Rejected)
+}
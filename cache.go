@@ -1,218 +1,1646 @@
 package mcache
 
 import (
+	"context"
+	"errors"
+	"math/rand"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrComputePanicked is returned by GetOrCompute calls waiting for the computation that panicked.
+var ErrComputePanicked = errors.New("mcache: value computation panicked")
+
+// Number of keys GetMany looks up under a single lock acquisition
+const getManyChunk = 1024
+
+// The map is rebuilt once it holds shrinkFactor times fewer items than at its peak,
+// if the peak was at least shrinkMinPeak items. Go maps never release memory on their own.
+const (
+	shrinkMinPeak = 4096
+	shrinkFactor  = 4
+)
+
+// Items expiring this soon after the timer fires are expired along with the due ones
+const expiryLookAhead = time.Millisecond
+
 type Cache[K comparable, V any] struct {
 	cache map[K]valuePtr[K, V] // Cached items
 	head  *item[K]             // The earliest item to evict, head of the queue
 	tail  *item[K]             // The latest item to evict
-	stop  chan struct{}        // The way to stop the timer
+	peak  int                  // The largest number of items since the map was created
+	size  int64                // Estimated memory held by the items
+	costs map[K]int64          // Sizes of values reported with UpdateCost
+	ver   uint64               // The last assigned entry version
+	timer Timer                // Expires the head item, created on first use and re-armed with Reset
+	opts  options
+	equal func(a, b V) bool // Values comparison
 	m     sync.RWMutex
+
+	onEvict func(K, V, Reason)                                 // Called for evicted items
+	sizer   func(K, V) int                                     // Estimates memory referenced by keys and values
+	loader  func(context.Context, K) (V, time.Duration, error) // Loads missing values
+	misses  *Cache[K, error]                                   // Keys the loader did not find, only with WithNegativeTTL
+
+	valueSizer func(V) int          // Measures values against WithMaxValueSize
+	oversized  func(K, V) (V, bool) // Handles values exceeding WithMaxValueSize
+
+	stats     cacheStats               // Operation counters
+	hot       *hotKeys[K]              // Access frequency tracking, only with WithHotKeyTracking
+	events    chan Event[K, V]         // Published changes, only with WithEvents
+	disabled  atomic.Bool              // When set, the cache always misses
+	reads     atomic.Pointer[sync.Map] // Lock-free read index, only with WithLockFreeReads
+	overrides []ttlOverride[K]         // TTL overrides for stored items
+	pins      map[K]bool               // Pinned keys, true if they expire as usual
+	ranked    bool                     // Some items have non-default priority
+
+	flights map[K]*flight[V] // Computations in progress for GetOrCompute
+	fm      sync.Mutex
+
+	closing chan struct{} // Closed to stop background work
+	closed  chan struct{} // Closed when background work is stopped
+	once    sync.Once
+
+	invalidator Invalidator[K] // Invalidation bus
+	unsubscribe func()         // Cancels the invalidation bus subscription
+}
+
+// ttlOverride replaces TTL of items with matching keys
+type ttlOverride[K comparable] struct {
+	match func(K) bool
+	ttl   time.Duration
+}
+
+// flight is a computation of a single key value, shared by concurrent GetOrCompute calls
+type flight[V any] struct {
+	done  chan struct{} // Closed once the value is computed
+	value V
+	err   error
 }
 
+// Values are stored inline in the map, so small values (integers, small arrays) need no extra allocation or indirection.
 type valuePtr[K comparable, V any] struct {
 	Value V        // The value that we cache
 	Ptr   *item[K] // Pointer to the node in the ordered queue for fast access
 }
 
+// KeyValue is a key and its value.
+type KeyValue[K comparable, V any] struct {
+	Key   K
+	Value V
+}
+
+// Item is a cached value with its expiration time.
+type Item[V any] struct {
+	Value   V
+	Expires time.Time
+}
+
 // ordered queue item
 type item[K comparable] struct {
-	Prev    *item[K]
-	Next    *item[K]
-	Key     K
-	Expires time.Time
+	Prev     *item[K]
+	Next     *item[K]
+	Key      K
+	Expires  time.Time
+	TTL      time.Duration // The original TTL, only kept for sliding expiration and refresh-ahead
+	Relaxed  bool          // The item expires at sweep interval boundary
+	Priority Priority      // Items of lower priority are evicted first
+	Info     *entryInfo    // Access metadata, only tracked with WithEntryInfo
+	Cost     time.Duration // How long the value took to load, only kept for XFetch
+	Version  uint64        // Changes whenever a value is stored, see GetVersioned
 }
 
 // New creates a news cache instance, using any comparable type for keys, and any type for values.
-func New[K comparable, V any]() *Cache[K, V] {
-	return &Cache[K, V]{
+func New[K comparable, V any](opts ...Option) *Cache[K, V] {
+	o := options{
+		sweep: defaultSweepInterval,
+		clock: systemClock{},
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	c := newCache[K, V](o)
+
+	if c.opts.invalidator != nil {
+		inv, ok := c.opts.invalidator.(Invalidator[K])
+		if !ok {
+			panic("mcache: WithInvalidator key type does not match cache key type")
+		}
+
+		c.invalidator = inv
+		c.unsubscribe = inv.Subscribe(func(key K) { c.Delete(key) })
+	}
+
+	if c.opts.snapshotSink != nil && c.opts.snapshotEvery > 0 {
+		c.closing = make(chan struct{})
+		c.closed = make(chan struct{})
+
+		go c.snapshotter()
+	}
+
+	return c
+}
+
+// newCache creates an empty cache with the options, without starting background work.
+func newCache[K comparable, V any](opts options) *Cache[K, V] {
+	c := &Cache[K, V]{
 		cache: make(map[K]valuePtr[K, V]),
-		stop:  make(chan struct{}),
+		opts:  opts,
+	}
+
+	if c.opts.clock == nil {
+		c.opts.clock = systemClock{}
+	}
+
+	if c.opts.equal == nil {
+		c.equal = func(a, b V) bool { return any(a) == any(b) }
+	} else if equal, ok := c.opts.equal.(func(a, b V) bool); ok {
+		c.equal = equal
+	} else {
+		panic("mcache: WithEqual function does not match cache value type")
+	}
+
+	if c.opts.onEvict != nil {
+		onEvict, ok := c.opts.onEvict.(func(K, V, Reason))
+		if !ok {
+			panic("mcache: WithEvictionCallback function does not match cache key and value types")
+		}
+
+		c.onEvict = onEvict
+	}
+
+	if c.opts.sizer != nil {
+		sizer, ok := c.opts.sizer.(func(K, V) int)
+		if !ok {
+			panic("mcache: WithSizer function does not match cache key and value types")
+		}
+
+		c.sizer = sizer
+	}
+
+	if c.opts.valueSizer != nil {
+		valueSizer, ok := c.opts.valueSizer.(func(V) int)
+		if !ok {
+			panic("mcache: WithMaxValueSize function does not match cache value type")
+		}
+
+		c.valueSizer = valueSizer
+	}
+
+	if c.opts.oversized != nil {
+		oversized, ok := c.opts.oversized.(func(K, V) (V, bool))
+		if !ok {
+			panic("mcache: WithOversizedValueHandler function does not match cache key and value types")
+		}
+
+		c.oversized = oversized
+	}
+
+	if c.opts.loader != nil {
+		loader, ok := c.opts.loader.(func(context.Context, K) (V, time.Duration, error))
+		if !ok {
+			panic("mcache: WithLoader function does not match cache key and value types")
+		}
+
+		c.loader = loader
+
+		if c.opts.negativeTTL > 0 {
+			c.misses = New[K, error](WithClock(c.opts.clock))
+		}
+	}
+
+	c.resetIndex()
+
+	if c.opts.events > 0 {
+		c.events = make(chan Event[K, V], c.opts.events)
+	}
+
+	if c.opts.hotWindow > 0 && c.opts.hotKeys > 0 {
+		c.hot = newHotKeys[K](c.opts.hotWindow, c.opts.hotKeys, c.opts.clock)
 	}
+
+	return c
+}
+
+// NewFromMap creates a new cache instance, populated with values from the map, all having the same TTL.
+func NewFromMap[K comparable, V any](m map[K]V, ttl time.Duration, opts ...Option) *Cache[K, V] {
+	c := New[K, V](opts...)
+
+	c.cache = make(map[K]valuePtr[K, V], len(m))
+
+	c.SetMany(m, ttl)
+
+	return c
 }
 
 // Set adds or replaces a value with key and given TTL.
 func (c *Cache[K, V]) Set(key K, value V, ttl time.Duration) {
 	c.m.Lock()
 
-	if _, ok := c.cache[key]; ok {
-		// We are replacing the item
-		c.delete(key)
+	c.set(key, value, c.now().Add(ttl), c.opts.precision, false)
+
+	c.unlock()
+}
+
+// SetUntil adds or replaces a value with key, expiring at the given time.
+func (c *Cache[K, V]) SetUntil(key K, value V, expireAt time.Time) {
+	c.m.Lock()
+
+	c.set(key, value, expireAt, c.opts.precision, true)
+
+	c.unlock()
+}
+
+// SetWithPrecision works as Set, but overrides the default expiration precision for the item.
+func (c *Cache[K, V]) SetWithPrecision(key K, value V, ttl time.Duration, p Precision) {
+	c.m.Lock()
+
+	c.set(key, value, c.now().Add(ttl), p, false)
+
+	c.unlock()
+}
+
+// SetGet works as Set, also returning the replaced value and true, or zero value and false if the key was not present.
+func (c *Cache[K, V]) SetGet(key K, value V, ttl time.Duration) (V, bool) {
+	expires := c.now().Add(ttl)
+
+	c.m.Lock()
+
+	old, ok := c.cache[key]
+	if ok && !c.alive(old.Ptr) {
+		old, ok = valuePtr[K, V]{}, false
 	}
 
-	i := &item[K]{
-		Key:     key,
-		Expires: time.Now().Add(ttl),
+	c.set(key, value, expires, c.opts.precision, false)
+
+	c.unlock()
+
+	return old.Value, ok
+}
+
+// GetOrSet returns the existing value and true if key is present, otherwise it sets the given value and returns it with false.
+func (c *Cache[K, V]) GetOrSet(key K, value V, ttl time.Duration) (V, bool) {
+	c.m.Lock()
+
+	if v, ok := c.cache[key]; ok && c.alive(v.Ptr) {
+		c.accessed(v.Ptr)
+
+		c.m.Unlock()
+
+		c.stats.lookup(true)
+
+		return v.Value, true
 	}
 
-	c.cache[key] = valuePtr[K, V]{
-		Value: value,
-		Ptr:   i,
+	c.set(key, value, c.now().Add(ttl), c.opts.precision, false)
+
+	c.unlock()
+
+	c.stats.lookup(false)
+
+	return value, false
+}
+
+// Get returns value and true, if key exists, of zero value and false if not found.
+// With sliding expiration, the key expiration is moved forward by its original TTL.
+// With a loader set, missing values are loaded and stored, and loading errors are reported as not found.
+// With refresh-ahead, values past the refresh point are reloaded in the background.
+// With XFetch, values close to expiration may be reloaded early.
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	value, ok := c.lookup(key)
+
+	switch {
+	case c.loader == nil:
+	case !ok:
+		var err error
+
+		value, err = c.load(context.Background(), key)
+		ok = err == nil
+	case c.opts.refreshAhead > 0:
+		c.refreshAhead(key)
+	case c.opts.xfetch > 0:
+		value = c.xfetch(key, value)
 	}
 
-	if c.head == nil {
-		c.head = i
-		c.tail = i
+	return value, ok
+}
 
-		c.setTimer()
+// GetOrDefault works as Get, but returns def if the key is not found.
+func (c *Cache[K, V]) GetOrDefault(key K, def V) V {
+	if value, ok := c.Get(key); ok {
+		return value
+	}
+
+	return def
+}
+
+// lookup works as Get, but does not call the loader.
+func (c *Cache[K, V]) lookup(key K) (V, bool) {
+	value, ok := c.get(key)
+
+	c.stats.lookup(ok)
+
+	if c.hot != nil {
+		c.hot.add(key)
+	}
+
+	return value, ok
+}
+
+// get works as Get, but does not count the lookup.
+func (c *Cache[K, V]) get(key K) (V, bool) {
+	if c.opts.sliding {
+		return c.getSliding(key)
+	}
+
+	if c.opts.lockFreeReads {
+		return c.getIndexed(key)
+	}
+
+	return c.getLocked(key)
+}
+
+// getLocked looks the key up under the read lock.
+func (c *Cache[K, V]) getLocked(key K) (V, bool) {
+	c.m.RLock()
+
+	value, ok := c.cache[key]
+	if ok && !c.alive(value.Ptr) {
+		value, ok = valuePtr[K, V]{}, false
+	}
+
+	if ok {
+		c.accessed(value.Ptr)
+	}
+
+	c.m.RUnlock()
+
+	return value.Value, ok
+}
+
+// GetWithExpiry works as Get, also returning the time the value expires at.
+func (c *Cache[K, V]) GetWithExpiry(key K) (V, time.Time, bool) {
+	c.m.RLock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.RUnlock()
+
+		c.stats.lookup(false)
+
+		var zero V
+
+		return zero, time.Time{}, false
+	}
+
+	expires := v.Ptr.Expires
+
+	c.accessed(v.Ptr)
+
+	c.m.RUnlock()
+
+	c.stats.lookup(true)
+
+	return v.Value, expires, true
+}
+
+// Peek works as GetWithExpiry, but does not count the lookup in stats, record the access, or call the loader.
+func (c *Cache[K, V]) Peek(key K) (V, time.Time, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		var zero V
+
+		return zero, time.Time{}, false
+	}
+
+	return v.Value, v.Ptr.Expires, true
+}
+
+// TTL returns time left until the key expires, and false if the key is not found.
+func (c *Cache[K, V]) TTL(key K) (time.Duration, bool) {
+	c.m.RLock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.RUnlock()
+
+		return 0, false
+	}
+
+	ttl := v.Ptr.Expires.Sub(c.now())
+
+	c.m.RUnlock()
+
+	if ttl < 0 {
+		ttl = 0
+	}
+
+	return ttl, true
+}
+
+// Has reports whether the key is present and has not expired.
+func (c *Cache[K, V]) Has(key K) bool {
+	c.m.RLock()
+
+	v, ok := c.cache[key]
+	ok = ok && c.alive(v.Ptr)
+
+	c.m.RUnlock()
+
+	return ok
+}
+
+// SetIfAbsent sets the value only if key is not present, returning true if the value was set.
+func (c *Cache[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	c.m.Lock()
+
+	if v, ok := c.cache[key]; ok && c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.set(key, value, c.now().Add(ttl), c.opts.precision, false)
+
+	c.unlock()
+
+	return true
+}
+
+// GetOrCompute returns the value if key is present, otherwise it calls fn to compute the value and its TTL, and stores it.
+// Concurrent calls for the same key wait for the first one to compute the value and get its result,
+// including the error, instead of calling fn again. If fn returns an error, nothing is stored and the error is returned.
+// If fn panics, the waiting calls get ErrComputePanicked.
+func (c *Cache[K, V]) GetOrCompute(key K, fn func() (V, time.Duration, error)) (V, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+
+	return c.compute(key, fn)
+}
+
+// compute calls fn to compute the missing value of the key, unless the computation is already in progress.
+func (c *Cache[K, V]) compute(key K, fn func() (V, time.Duration, error)) (V, error) {
+	f, leader := c.join(key)
+	if !leader {
+		<-f.done
+
+		return f.value, f.err
+	}
+
+	defer c.land(key, f)
+
+	// The value could have been computed by the previous computation that has just finished
+	if value, ok := c.get(key); ok {
+		f.value, f.err = value, nil
+
+		return value, nil
+	}
+
+	var ttl time.Duration
+
+	f.value, ttl, f.err = fn()
+	if f.err == nil {
+		c.Set(key, f.value, ttl)
+	}
+
+	return f.value, f.err
+}
+
+// GetWithFallback returns the value if key is present, otherwise it tries fallbacks in order until one of them succeeds.
+// The value returned by the fallback is stored with the default TTL, if one is set with WithDefaultTTL.
+func (c *Cache[K, V]) GetWithFallback(key K, fallbacks ...func(K) (V, bool)) (V, bool) {
+	if value, ok := c.lookup(key); ok {
+		return value, true
+	}
+
+	for _, fallback := range fallbacks {
+		value, ok := fallback(key)
+		if !ok {
+			continue
+		}
+
+		c.m.Lock()
+
+		if c.opts.ttl > 0 {
+			c.set(key, value, c.now().Add(c.opts.ttl), c.opts.precision, false)
+		}
+
+		c.unlock()
+
+		return value, true
+	}
+
+	var zero V
+
+	return zero, false
+}
+
+// Compute atomically sets the value of the key to the one returned by fn, which gets the current value, if any.
+// If fn returns true as the last value, the key is deleted instead. The cache is locked while fn is executed,
+// so fn must not call the cache methods. Returns the resulting value and true if the key is present after the call.
+func (c *Cache[K, V]) Compute(key K, fn func(old V, exists bool) (value V, ttl time.Duration, delete bool)) (V, bool) {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if ok && !c.alive(v.Ptr) {
+		v, ok = valuePtr[K, V]{}, false
+	}
+
+	value, ttl, del := fn(v.Value, ok)
+	if del {
+		if ok {
+			c.publish(EventDelete, key, v.Value)
+			c.drop(key)
+		}
+
+		c.m.Unlock()
+
+		var zero V
+
+		return zero, false
+	}
+
+	c.set(key, value, c.now().Add(ttl), c.opts.precision, false)
+
+	c.unlock()
+
+	return value, true
+}
+
+func (c *Cache[K, V]) getSliding(key K) (V, bool) {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		var zero V
+
+		return zero, false
+	}
+
+	c.reschedule(v.Ptr, c.deadline(c.now().Add(v.Ptr.TTL), v.Ptr.Relaxed))
+	c.accessed(v.Ptr)
+
+	c.m.Unlock()
+
+	return v.Value, true
+}
+
+// GetStale works as Get, but also returns values that have expired and are kept for the grace period.
+// The last returned value reports whether the value has expired.
+func (c *Cache[K, V]) GetStale(key K) (V, bool, bool) {
+	c.m.RLock()
+
+	value, ok := c.cache[key]
+	if ok && c.disabled.Load() {
+		value, ok = valuePtr[K, V]{}, false
+	}
+
+	expired := ok && !c.alive(value.Ptr)
+
+	c.m.RUnlock()
+
+	return value.Value, ok, expired
+}
+
+// GetMany returns key/value pairs as a map. Will not return non-existing keys/expired values.
+// Keys are looked up in chunks, releasing the lock in between, so large batches do not starve writers.
+// Each chunk is a consistent view of the cache. Lookups are counted, extend sliding TTLs, and are tracked
+// as hot keys the same way Get does, but the loader is not called.
+func (c *Cache[K, V]) GetMany(keys ...K) map[K]V {
+	values, _ := c.GetManyBudget(0, keys...)
+
+	return values
+}
+
+// GetManyBudget works as GetMany, but stops once the lock was held longer than maxLockHold in total.
+// It returns values found so far and the number of processed keys, so the call can be continued with the rest of keys.
+// Zero maxLockHold means no limit.
+func (c *Cache[K, V]) GetManyBudget(maxLockHold time.Duration, keys ...K) (map[K]V, int) {
+	values := make(map[K]V)
+
+	var held time.Duration
+
+	for start := 0; start < len(keys); start += getManyChunk {
+		if maxLockHold > 0 && held >= maxLockHold {
+			return values, start
+		}
+
+		end := start + getManyChunk
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		hits, d := c.lookupChunk(keys[start:end], values)
+		held += d
+
+		c.stats.hits.Add(uint64(hits))
+		c.stats.misses.Add(uint64(end - start - hits))
+
+		if c.hot != nil {
+			for _, key := range keys[start:end] {
+				c.hot.add(key)
+			}
+		}
+	}
+
+	return values, len(keys)
+}
+
+// lookupChunk looks the keys up under a single lock acquisition, storing found values. It returns the number of hits,
+// counting every lookup of repeated keys, and for how long the lock was held.
+// With sliding expiration the write lock is taken to extend TTLs of the found items.
+func (c *Cache[K, V]) lookupChunk(keys []K, values map[K]V) (hits int, held time.Duration) {
+	if c.opts.sliding {
+		c.m.Lock()
+		defer c.m.Unlock()
+	} else {
+		c.m.RLock()
+		defer c.m.RUnlock()
+	}
+
+	locked := time.Now()
+
+	for _, key := range keys {
+		v, ok := c.cache[key]
+		if !ok || !c.alive(v.Ptr) {
+			continue
+		}
+
+		values[key] = v.Value
+		hits++
+
+		if c.opts.sliding {
+			c.reschedule(v.Ptr, c.deadline(c.now().Add(v.Ptr.TTL), v.Ptr.Relaxed))
+		}
+
+		c.accessed(v.Ptr)
+	}
+
+	return hits, time.Since(locked)
+}
+
+// MGet returns values in the order of the given keys, along with the mask telling which keys were found.
+// Values of keys not found are zero values.
+func (c *Cache[K, V]) MGet(keys ...K) ([]V, []bool) {
+	values := make([]V, len(keys))
+	found := make([]bool, len(keys))
+
+	c.m.RLock()
+
+	hits := 0
+
+	for k := range keys {
+		if v, ok := c.cache[keys[k]]; ok && c.alive(v.Ptr) {
+			values[k], found[k] = v.Value, true
+			hits++
+
+			c.accessed(v.Ptr)
+		}
+	}
+
+	c.m.RUnlock()
+
+	c.stats.hits.Add(uint64(hits))
+	c.stats.misses.Add(uint64(len(keys) - hits))
+
+	return values, found
+}
+
+// Swap sets the new value returning the old one. Will return false if key is not found.
+func (c *Cache[K, V]) Swap(key K, value V) (V, bool) {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return value, false
+	}
+
+	oldValue := v.Value
+
+	c.store(key, valuePtr[K, V]{
+		Value: value,
+		Ptr:   v.Ptr,
+	})
+
+	c.updated(v.Ptr)
+	c.publish(EventUpdate, key, value)
+
+	c.unlock()
+
+	return oldValue, true
+}
+
+// SwapWithTTL works as Swap, also setting new TTL for the key in the same operation.
+func (c *Cache[K, V]) SwapWithTTL(key K, value V, ttl time.Duration) (V, bool) {
+	expires := c.now().Add(ttl)
+
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return value, false
+	}
+
+	oldValue := v.Value
+
+	c.store(key, valuePtr[K, V]{
+		Value: value,
+		Ptr:   v.Ptr,
+	})
+
+	c.reschedule(v.Ptr, c.deadline(c.clamp(expires), v.Ptr.Relaxed))
+	c.updated(v.Ptr)
+	c.publish(EventUpdate, key, value)
+
+	c.unlock()
+
+	return oldValue, true
+}
+
+// CompareAndSwap replaces the value with new one if the current value is equal to old, keeping the TTL.
+// Returns true if the value was swapped.
+func (c *Cache[K, V]) CompareAndSwap(key K, old, new V) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) || !c.equal(v.Value, old) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.store(key, valuePtr[K, V]{
+		Value: new,
+		Ptr:   v.Ptr,
+	})
+
+	c.updated(v.Ptr)
+	c.publish(EventUpdate, key, new)
+
+	c.unlock()
+
+	return true
+}
+
+// Delete removes value from thr cache.
+func (c *Cache[K, V]) Delete(key K) (ok bool) {
+	c.m.Lock()
+
+	c.publishRemoval(EventDelete, key)
+
+	ok = c.drop(key)
+
+	c.m.Unlock()
+
+	if ok {
+		c.stats.deletes.Add(1)
+	}
+
+	return
+}
+
+// CompareAndDelete deletes the key if its value is equal to the given one, returning true if the key was deleted.
+func (c *Cache[K, V]) CompareAndDelete(key K, value V) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) || !c.equal(v.Value, value) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.publish(EventDelete, key, v.Value)
+	c.drop(key)
+
+	c.m.Unlock()
+
+	c.stats.deletes.Add(1)
+
+	return true
+}
+
+// GetAndDelete returns value and true, and deletes the key if it was found, of zero value and false if the key not found.
+func (c *Cache[K, V]) GetAndDelete(key K) (V, bool) {
+	c.m.Lock()
+
+	value, ok := c.cache[key]
+	if !ok || !c.alive(value.Ptr) {
+		c.m.Unlock()
+
+		var zero V
+
+		return zero, false
+	}
+
+	c.publish(EventDelete, key, value.Value)
+	c.delete(key)
+
+	c.m.Unlock()
+
+	c.stats.deletes.Add(1)
+
+	return value.Value, true
+}
+
+// Update sets new value for key without changing TTL, returning false if key not found.
+func (c *Cache[K, V]) Update(key K, value V) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	v.Value = value
+	c.store(key, v)
+
+	c.updated(v.Ptr)
+	c.publish(EventUpdate, key, v.Value)
+
+	c.unlock()
+
+	return true
+}
+
+// ComputeIfPresent atomically replaces the value with the one returned by fn without changing TTL.
+// Returns false if key is not found. The cache is locked while fn is executed, so fn must not call the cache methods.
+func (c *Cache[K, V]) ComputeIfPresent(key K, fn func(V) V) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	v.Value = fn(v.Value)
+	c.store(key, v)
+
+	c.updated(v.Ptr)
+	c.publish(EventUpdate, key, v.Value)
+
+	c.unlock()
+
+	return true
+}
+
+// Refresh sets new TTL for the given key, returning true if the key (still) exists.
+func (c *Cache[K, V]) Refresh(key K, ttl time.Duration) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.reschedule(v.Ptr, c.deadline(c.clamp(c.now().Add(ttl)), v.Ptr.Relaxed))
+
+	c.m.Unlock()
+
+	return true
+}
+
+// Touch sets the key TTL to the default one set with WithDefaultTTL.
+// Returns false if the key is not found, or the default TTL is not set.
+func (c *Cache[K, V]) Touch(key K) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) || c.opts.ttl <= 0 {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.reschedule(v.Ptr, c.deadline(c.clamp(c.now().Add(c.opts.ttl)), v.Ptr.Relaxed))
+
+	c.m.Unlock()
+
+	return true
+}
+
+// Extend adds delta to the key expiration time, returning false if the key is not found.
+// Unlike Refresh, the new TTL is relative to the current expiration time rather than now.
+// The resulting TTL is limited as set with WithTTLLimits.
+func (c *Cache[K, V]) Extend(key K, delta time.Duration) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.reschedule(v.Ptr, c.deadline(c.clamp(v.Ptr.Expires.Add(delta)), v.Ptr.Relaxed))
+
+	c.m.Unlock()
+
+	return true
+}
+
+// OverrideTTL sets TTL of all items with keys matching the predicate, returning the number of updated items.
+// If future is true, the TTL also replaces the one given when storing matching keys later, until ClearTTLOverrides is called.
+func (c *Cache[K, V]) OverrideTTL(match func(K) bool, ttl time.Duration, future bool) int {
+	expires := c.now().Add(ttl)
+
+	c.m.Lock()
+
+	if future {
+		c.overrides = append(c.overrides, ttlOverride[K]{
+			match: match,
+			ttl:   ttl,
+		})
+	}
+
+	head := c.head
+
+	var items []*item[K]
+
+	for n := c.head; n != nil; {
+		next := n.Next
+
+		if match(n.Key) {
+			c.remove(n)
+			n.Expires = c.deadline(expires, n.Relaxed)
+			c.index(n.Key)
+			items = append(items, n)
+		}
+
+		n = next
+	}
+
+	c.spliceMany(items)
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.m.Unlock()
+
+	return len(items)
+}
+
+// ClearTTLOverrides removes TTL overrides set with OverrideTTL for future items.
+func (c *Cache[K, V]) ClearTTLOverrides() {
+	c.m.Lock()
+
+	c.overrides = nil
+
+	c.m.Unlock()
+}
+
+// PopOldest removes and returns the item that expires earliest. Returns false if the cache is empty.
+func (c *Cache[K, V]) PopOldest() (K, V, bool) {
+	c.m.Lock()
+
+	return c.pop(c.oldest())
+}
+
+// PopNewest removes and returns the item that expires latest. Returns false if the cache is empty.
+func (c *Cache[K, V]) PopNewest() (K, V, bool) {
+	c.m.Lock()
+
+	n := c.tail
+	if n != nil && !c.alive(n) {
+		n = nil
+	}
+
+	return c.pop(n)
+}
+
+// PeekOldest returns the item that expires earliest, with its expiration time, without removing it.
+// Returns false if the cache is empty.
+func (c *Cache[K, V]) PeekOldest() (K, V, time.Time, bool) {
+	c.m.RLock()
+
+	n := c.oldest()
+	if n == nil {
+		c.m.RUnlock()
+
+		var (
+			key   K
+			value V
+		)
+
+		return key, value, time.Time{}, false
+	}
+
+	key, value, expires := n.Key, c.cache[n.Key].Value, n.Expires
+
+	c.m.RUnlock()
+
+	return key, value, expires, true
+}
+
+// NextExpiration returns the time the earliest item expires at. Returns false if the cache is empty.
+func (c *Cache[K, V]) NextExpiration() (time.Time, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	if n := c.oldest(); n != nil {
+		return n.Expires, true
+	}
+
+	return time.Time{}, false
+}
+
+// oldest returns the earliest item to expire, skipping items which have already expired.
+func (c *Cache[K, V]) oldest() *item[K] {
+	n := c.head
+	for n != nil && !c.alive(n) {
+		n = n.Next
+	}
+
+	return n
+}
+
+// pop removes the item and unlocks the cache.
+func (c *Cache[K, V]) pop(n *item[K]) (K, V, bool) {
+	if n == nil {
+		c.m.Unlock()
+
+		var (
+			key   K
+			value V
+		)
+
+		return key, value, false
+	}
+
+	key, value := n.Key, c.cache[n.Key].Value
+
+	c.publishRemoval(EventDelete, key)
+	c.drop(key)
+
+	c.m.Unlock()
+
+	c.stats.deletes.Add(1)
+
+	return key, value, true
+}
+
+// Evict removes (at most) n items that expire earliest, returning the number of actually evicted items.
+// Items of lower priority are evicted first, pinned items are skipped.
+func (c *Cache[K, V]) Evict(n int) (evicted int) {
+	c.m.Lock()
+
+	c.stopTimer()
+
+	var items []KeyValue[K, V]
+
+	c.victims(func(i *item[K]) bool {
+		if evicted >= n {
+			return false
+		}
+
+		if c.onEvict != nil {
+			items = append(items, KeyValue[K, V]{Key: i.Key, Value: c.cache[i.Key].Value})
+		}
+
+		c.publishRemoval(EventEvict, i.Key)
+
+		c.delete(i.Key)
+
+		evicted++
+
+		return true
+	})
+
+	if c.head != nil {
+		c.setTimer()
+	}
+
+	logger := c.opts.logger
+
+	c.m.Unlock()
+
+	c.stats.evictions.Add(uint64(evicted))
+
+	if logger != nil && evicted > 0 {
+		logger.Debug("mcache: items evicted", "count", evicted)
+	}
+
+	c.notify(items, Evicted, logger)
+
+	return
+}
+
+// Sweep removes all items that are due to expire, returning the number of removed items.
+// Items kept for the grace period are only removed once it is over.
+func (c *Cache[K, V]) Sweep() int {
+	now := c.now()
+
+	c.m.Lock()
+
+	head := c.head
+
+	var (
+		items []KeyValue[K, V]
+		swept int
+	)
+
+	for c.head != nil && !now.Before(c.head.Expires.Add(c.opts.grace)) {
+		if c.held(c.head) {
+			continue
+		}
+
+		if c.onEvict != nil {
+			items = append(items, KeyValue[K, V]{Key: c.head.Key, Value: c.cache[c.head.Key].Value})
+		}
+
+		c.publishRemoval(EventExpire, c.head.Key)
+
+		c.delete(c.head.Key)
+
+		swept++
+	}
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	logger := c.opts.logger
+
+	c.m.Unlock()
+
+	c.stats.expirations.Add(uint64(swept))
+
+	if logger != nil && swept > 0 {
+		logger.Debug("mcache: items swept", "count", swept)
+	}
+
+	c.notify(items, Expired, logger)
+
+	return swept
+}
+
+// Clear removes all items from the cache.
+func (c *Cache[K, V]) Clear() {
+	c.m.Lock()
+
+	c.stopTimer()
+
+	var items []KeyValue[K, V]
+
+	if c.onEvict != nil {
+		items = make([]KeyValue[K, V], 0, len(c.cache))
+
+		for n := c.head; n != nil; n = n.Next {
+			items = append(items, KeyValue[K, V]{Key: n.Key, Value: c.cache[n.Key].Value})
+		}
+	}
+
+	c.cache = make(map[K]valuePtr[K, V])
+	c.head, c.tail = nil, nil
+	c.peak, c.size = 0, 0
+	c.costs = nil
+	c.resetIndex()
+
+	var (
+		key   K
+		value V
+	)
+
+	c.publish(EventClear, key, value)
+
+	logger := c.opts.logger
+
+	c.m.Unlock()
+
+	if c.misses != nil {
+		c.misses.Clear()
+	}
+
+	c.notify(items, Cleared, logger)
+}
+
+// ClearAsync removes all items from the cache in constant time, leaving the old items to the garbage collector.
+// If eviction callback is set, it is called for the cleared items in background.
+// Returns channel which is closed once all callbacks are called.
+func (c *Cache[K, V]) ClearAsync() <-chan struct{} {
+	c.m.Lock()
+
+	c.stopTimer()
+
+	cache, head := c.cache, c.head
+
+	c.cache = make(map[K]valuePtr[K, V])
+	c.head, c.tail = nil, nil
+	c.peak, c.size = 0, 0
+	c.costs = nil
+	c.resetIndex()
+
+	var (
+		key   K
+		value V
+	)
+
+	c.publish(EventClear, key, value)
+
+	logger := c.opts.logger
+
+	c.m.Unlock()
+
+	done := make(chan struct{})
+
+	if c.onEvict == nil {
+		close(done)
+
+		return done
+	}
+
+	go func() {
+		defer close(done)
+
+		// Nobody else references the old items now, so no locking is needed
+		for n := head; n != nil; n = n.Next {
+			c.callback(n.Key, cache[n.Key].Value, Cleared, logger)
+		}
+	}()
+
+	return done
+}
+
+// Range iterates over key/value pairs using supplied function until it returns false.
+// Values are provided in the order of eviction. It is safe to manipulate the cache within the function.
+func (c *Cache[K, V]) Range(fn func(K, V) bool) {
+	c.RangeWithExpiry(func(key K, value V, _ time.Time) bool {
+		return fn(key, value)
+	})
+}
+
+// RangeWithExpiry works as Range, also providing expiration time of each item.
+func (c *Cache[K, V]) RangeWithExpiry(fn func(K, V, time.Time) bool) {
+	c.m.RLock()
+	keys := make([]K, 0, len(c.cache))
+	for n := c.head; n != nil; n = n.Next {
+		keys = append(keys, n.Key)
+	}
+	c.m.RUnlock()
+
+	c.rangeKeys(keys, fn)
+}
+
+// RangeReverse works as Range, but starts with the item that expires latest.
+func (c *Cache[K, V]) RangeReverse(fn func(K, V) bool) {
+	c.m.RLock()
+	keys := make([]K, 0, len(c.cache))
+	for n := c.tail; n != nil; n = n.Prev {
+		keys = append(keys, n.Key)
+	}
+	c.m.RUnlock()
+
+	c.rangeKeys(keys, func(key K, value V, _ time.Time) bool {
+		return fn(key, value)
+	})
+}
+
+// ExpiringWithin iterates, same as RangeWithExpiry, over items expiring within the given period from now.
+func (c *Cache[K, V]) ExpiringWithin(d time.Duration, fn func(K, V, time.Time) bool) {
+	until := c.now().Add(d)
+
+	c.m.RLock()
+	var keys []K
+	for n := c.head; n != nil && n.Expires.Before(until); n = n.Next {
+		keys = append(keys, n.Key)
+	}
+	c.m.RUnlock()
+
+	c.rangeKeys(keys, fn)
+}
+
+// rangeKeys calls fn for each of the keys still present in the cache, until it returns false.
+func (c *Cache[K, V]) rangeKeys(keys []K, fn func(K, V, time.Time) bool) {
+	for k := range keys {
+		c.m.RLock()
+		value, ok := c.cache[keys[k]]
+		ok = ok && c.alive(value.Ptr)
+
+		var expires time.Time
+		if ok {
+			expires = value.Ptr.Expires
+		}
+		c.m.RUnlock()
+
+		if !ok {
+			continue
+		}
+
+		if !fn(keys[k], value.Value, expires) {
+			break
+		}
+	}
+}
+
+// Keys returns all keys in the order of eviction.
+func (c *Cache[K, V]) Keys() []K {
+	c.m.RLock()
+
+	keys := make([]K, 0, len(c.cache))
+
+	for n := c.head; n != nil; n = n.Next {
+		if c.alive(n) {
+			keys = append(keys, n.Key)
+		}
+	}
+
+	c.m.RUnlock()
+
+	return keys
+}
+
+// Values returns all values in the order of eviction.
+func (c *Cache[K, V]) Values() []V {
+	c.m.RLock()
+
+	values := make([]V, 0, len(c.cache))
+
+	for n := c.head; n != nil; n = n.Next {
+		if c.alive(n) {
+			values = append(values, c.cache[n.Key].Value)
+		}
+	}
+
+	c.m.RUnlock()
+
+	return values
+}
+
+// Items returns a point-in-time snapshot of all items with their expiration times.
+func (c *Cache[K, V]) Items() map[K]Item[V] {
+	c.m.RLock()
+
+	items := make(map[K]Item[V], len(c.cache))
+
+	for key, v := range c.cache {
+		if c.alive(v.Ptr) {
+			items[key] = Item[V]{
+				Value:   v.Value,
+				Expires: v.Ptr.Expires,
+			}
+		}
+	}
+
+	c.m.RUnlock()
+
+	return items
+}
+
+// Rekey replaces value's key. Returns false if the old key is not present.
+func (c *Cache[K, V]) Rekey(oldKey, newKey K) bool {
+	c.m.Lock()
+
+	item, ok := c.cache[oldKey]
+	if !ok {
+		c.m.Unlock()
+		return false
+	}
+
+	if oldKey == newKey {
+		c.m.Unlock()
+		return true
+	}
+
+	replaced, exists := c.cache[newKey]
+	if exists {
+		c.size -= c.entrySize(newKey, replaced.Value)
+		delete(c.costs, newKey)
+
+		wasFirst := c.head == replaced.Ptr
+
+		c.remove(replaced.Ptr)
+
+		if wasFirst && c.head != nil {
+			c.setTimer()
+		}
+	}
 
-		c.m.Unlock()
+	oldSize := c.entrySize(oldKey, item.Value)
 
-		return
+	if cost, ok := c.costs[oldKey]; ok {
+		c.costs[newKey] = cost
+		delete(c.costs, oldKey)
 	}
 
-	// Start from the tail, it is the most likely new item will have TTL past the last existing item
-	for n := c.tail; ; n = n.Prev {
-		if n.Expires.Before(i.Expires) {
-			c.insertAfter(i, n)
+	c.size += c.entrySize(newKey, item.Value) - oldSize
 
-			break
-		}
-		// The new item is the earliest to evict
-		if n.Prev == nil {
-			c.insertBefore(i, n)
-			c.setTimer()
+	item.Ptr.Key = newKey
+	c.cache[newKey] = item
+	delete(c.cache, oldKey)
 
-			break
-		}
+	c.index(newKey)
+	c.unindex(oldKey)
+
+	c.publish(EventDelete, oldKey, item.Value)
+
+	if exists {
+		c.publish(EventUpdate, newKey, item.Value)
+	} else {
+		c.publish(EventSet, newKey, item.Value)
 	}
 
 	c.m.Unlock()
+
+	return true
 }
 
-// Get returns value and true, if key exists, of zero value and false if not found.
-func (c *Cache[K, V]) Get(key K) (V, bool) {
+// Filter returns a new cache with the same options, holding items for which fn returns true.
+// The items keep their expiration times, expired items kept for the grace period are skipped.
+// The cache is locked while fn is executed, so fn must not call the cache methods.
+// The new cache does not make periodic snapshots and is not subscribed to the invalidation bus.
+func (c *Cache[K, V]) Filter(fn func(K, V) bool) *Cache[K, V] {
 	c.m.RLock()
 
-	value, ok := c.cache[key]
-
-	c.m.RUnlock()
-
-	return value.Value, ok
-}
-
-// GetMany returns key/value pairs as a map. Will not return non-existing keys/expired values.
-func (c *Cache[K, V]) GetMany(keys ...K) map[K]V {
-	values := make(map[K]V)
+	f := c.derive()
 
-	c.m.RLock()
+	for n := c.head; n != nil; n = n.Next {
+		if !c.alive(n) {
+			continue
+		}
 
-	for k := range keys {
-		if v, ok := c.cache[keys[k]]; ok {
-			values[keys[k]] = v.Value
+		if v := c.cache[n.Key]; fn(n.Key, v.Value) {
+			f.append(n, v.Value)
 		}
 	}
 
 	c.m.RUnlock()
 
-	return values
+	if f.head != nil {
+		f.setTimer()
+	}
+
+	return f
 }
 
-// Swap sets the new value returning the old one. Will return false if key is not found.
-func (c *Cache[K, V]) Swap(key K, value V) (V, bool) {
+// Clone returns a copy of the cache with the same options and items, the items keep their expiration times.
+func (c *Cache[K, V]) Clone() *Cache[K, V] {
+	return c.Filter(func(K, V) bool { return true })
+}
+
+// Reconfigure applies options to a live cache. Only grace period, default precision, sweep interval, default TTL,
+// TTL jitter, TTL limits, logger, and memory budget can be changed, other options are ignored, including the sizer
+// given to WithMemoryBudget. Changes apply to items stored afterwards, except the grace period and the logger,
+// which apply right away, and the memory budget: items are evicted at once if the cache no longer fits it.
+func (c *Cache[K, V]) Reconfigure(opts ...Option) {
 	c.m.Lock()
 
-	v, ok := c.cache[key]
-	if !ok {
-		c.m.Unlock()
+	o := c.opts
 
-		return value, false
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	oldValue := v.Value
+	c.opts.reconfigure(o)
 
-	c.cache[key] = valuePtr[K, V]{
-		Value: value,
-		Ptr:   v.Ptr,
+	if c.head != nil {
+		c.setTimer()
 	}
 
-	c.m.Unlock()
+	c.unlock()
+}
 
-	return oldValue, true
+// Disable makes the cache behave as always empty, keeping the stored items. New values are not stored,
+// but deleting and replacing keys still removes the stored items, so they are not outdated once the cache is enabled again.
+// Stored items keep expiring as usual.
+func (c *Cache[K, V]) Disable() {
+	c.disabled.Store(true)
 }
 
-// Delete removes value from thr cache.
-func (c *Cache[K, V]) Delete(key K) (ok bool) {
-	c.m.Lock()
+// Enable brings back the cache disabled with Disable.
+func (c *Cache[K, V]) Enable() {
+	c.disabled.Store(false)
+}
 
-	timerResetNeeded := c.head != nil && c.head.Key == key
+// Disabled reports whether the cache is disabled.
+func (c *Cache[K, V]) Disabled() bool {
+	return c.disabled.Load()
+}
 
-	ok = c.delete(key)
+// Close stops background work started by options, such as periodic snapshots and invalidation bus subscription,
+// and waits for it to finish. The cache remains usable after Close.
+func (c *Cache[K, V]) Close() {
+	c.once.Do(func() {
+		if c.unsubscribe != nil {
+			c.unsubscribe()
+		}
 
-	if c.head != nil && timerResetNeeded {
-		c.setTimer()
-	}
+		if c.closing != nil {
+			close(c.closing)
+			<-c.closed
+		}
+	})
+}
 
-	c.m.Unlock()
+// Len returns number of items currently stored in the cache, including expired items kept for the grace period.
+func (c *Cache[K, V]) Len() int {
+	c.m.RLock()
+	defer c.m.RUnlock()
 
-	return
+	return len(c.cache)
 }
 
-// GetAndDelete returns value and true, and deletes the key if it was found, of zero value and false if the key not found.
-func (c *Cache[K, V]) GetAndDelete(key K) (V, bool) {
-	c.m.Lock()
+func (c *Cache[K, V]) set(key K, value V, expires time.Time, p Precision, absolute bool) {
+	if c.disabled.Load() {
+		// Do not store the value, but do not keep the outdated one either
+		c.drop(key)
 
-	value, ok := c.cache[key]
-	if !ok {
-		c.m.Unlock()
+		return
+	}
 
-		return value.Value, false
+	value, admitted := c.admit(key, value)
+	if !admitted {
+		c.drop(key)
+
+		return
 	}
 
-	c.delete(key)
+	replaced, ok := c.cache[key]
+	if ok {
+		// We are replacing the item
+		c.delete(key)
+	}
 
-	c.m.Unlock()
+	i := c.newItem(key, expires, p == Relaxed, absolute)
 
-	return value.Value, true
-}
+	c.track(i, replaced.Ptr)
 
-// Update sets new value for key without changing TTL, returning false if key not found.
-func (c *Cache[K, V]) Update(key K, value V) bool {
-	c.m.Lock()
+	c.store(key, valuePtr[K, V]{
+		Value: value,
+		Ptr:   i,
+	})
 
-	v, ok := c.cache[key]
-	if !ok {
-		c.m.Unlock()
+	c.stats.sets.Add(1)
 
-		return false
+	if ok {
+		c.publish(EventUpdate, key, value)
+	} else {
+		c.publish(EventSet, key, value)
 	}
 
-	v.Value = value
+	if c.head == nil {
+		c.head = i
+		c.tail = i
 
-	c.m.Unlock()
+		c.setTimer()
 
-	return true
-}
+		return
+	}
 
-// Refresh sets new TTL for the given key, returning true if the key (still) exists.
-func (c *Cache[K, V]) Refresh(key K, ttl time.Duration) bool {
-	c.m.Lock()
+	// Start from the tail, it is the most likely new item will have TTL past the last existing item
+	for n := c.tail; ; n = n.Prev {
+		if !i.Expires.Before(n.Expires) {
+			c.insertAfter(i, n)
 
-	v, ok := c.cache[key]
-	if !ok {
-		c.m.Unlock()
+			break
+		}
+		// The new item is the earliest to evict
+		if n.Prev == nil {
+			c.insertBefore(i, n)
+			c.setTimer()
 
-		return false
+			break
+		}
 	}
+}
 
-	expires := time.Now().Add(ttl)
-	wasFirst := c.head.Key == key
+// reschedule moves the item to the new place in the queue according to the new expiration time.
+func (c *Cache[K, V]) reschedule(i *item[K], expires time.Time) {
+	wasFirst := c.head == i
 
-	start := c.remove(v.Ptr) // Remove the item from the queue to put into a new place
+	start := c.remove(i) // Remove the item from the queue to put into a new place
 
-	if expires.After(v.Ptr.Expires) { // Move towards the tail
+	if start == nil { // It was the only item
+		c.head, c.tail = i, i
+	} else if expires.After(i.Expires) { // Move towards the tail
 		for n := start; ; n = n.Next {
 			if expires.Before(n.Expires) {
-				c.insertBefore(v.Ptr, n)
+				c.insertBefore(i, n)
 
 				break
 			}
 
 			if n.Next == nil {
-				c.insertAfter(v.Ptr, n)
+				c.insertAfter(i, n)
 
 				break
 			}
@@ -220,128 +1648,264 @@ func (c *Cache[K, V]) Refresh(key K, ttl time.Duration) bool {
 	} else { // Move it towards the head
 		for n := start; ; n = n.Prev {
 			if expires.After(n.Expires) {
-				c.insertAfter(v.Ptr, n)
+				c.insertAfter(i, n)
 
 				break
 			}
 
 			if n.Prev == nil {
-				c.insertBefore(v.Ptr, n)
+				c.insertBefore(i, n)
 
 				break
 			}
 		}
 	}
 
-	v.Ptr.Expires = expires
+	i.Expires = expires
 
-	if wasFirst || c.head.Key == key {
+	c.index(i.Key)
+
+	if wasFirst || c.head == i {
 		c.setTimer()
 	}
+}
 
-	c.m.Unlock()
+// derive creates an empty cache with the same options, initialized as New does, but without periodic snapshots
+// and invalidation bus subscription. Must be called under the lock.
+func (c *Cache[K, V]) derive() *Cache[K, V] {
+	return newCache[K, V](c.opts)
+}
 
-	return true
+// append adds a copy of the item to the tail of the queue, the item must expire no earlier than the current tail.
+func (c *Cache[K, V]) append(n *item[K], value V) {
+	i := &item[K]{
+		Key:      n.Key,
+		Expires:  n.Expires,
+		TTL:      n.TTL,
+		Relaxed:  n.Relaxed,
+		Info:     n.Info.clone(),
+		Priority: n.Priority,
+		Cost:     n.Cost,
+	}
+
+	c.store(i.Key, valuePtr[K, V]{
+		Value: value,
+		Ptr:   i,
+	})
+
+	if c.tail == nil {
+		c.head, c.tail = i, i
+	} else {
+		c.insertAfter(i, c.tail)
+	}
 }
 
-// Evict removes (at most) n items that expire earliest, returning the number of actually evicted items.
-func (c *Cache[K, V]) Evict(n int) (evicted int) {
-	c.m.Lock()
+// newItem creates queue item for the key, applying jitter, TTL limits, overrides, and expiration precision.
+// Absolute expiration times given by the caller are neither jittered nor limited.
+func (c *Cache[K, V]) newItem(key K, expires time.Time, relaxed, absolute bool) *item[K] {
+	if !absolute {
+		if c.opts.jitter > 0 {
+			now := c.now()
+			ttl := expires.Sub(now)
+			expires = now.Add(ttl + time.Duration((rand.Float64()*2-1)*c.opts.jitter*float64(ttl)))
+		}
 
-	select {
-	case c.stop <- struct{}{}:
-	default:
+		expires = c.clamp(expires)
 	}
 
-	for evicted = 0; evicted < n && c.head != nil && c.delete(c.head.Key); evicted++ {
+	i := &item[K]{
+		Key:     key,
+		Expires: c.override(key, expires),
+		Relaxed: relaxed,
 	}
 
-	if evicted > 0 && c.head != nil {
-		c.setTimer()
+	if c.opts.sliding || c.opts.refreshAhead > 0 {
+		i.TTL = i.Expires.Sub(c.now())
 	}
 
-	c.m.Unlock()
+	i.Expires = c.deadline(i.Expires, relaxed)
 
-	return
+	return i
 }
 
-// Range iterates over key/value pairs using supplied function until it returns false.
-// Values are provided in the order of eviction. It is safe to manipulate the cache within the function.
-func (c *Cache[K, V]) Range(fn func(K, V) bool) {
-	c.m.RLock()
-	keys := make([]K, 0, len(c.cache))
-	for n := c.head; n != nil; n = n.Next {
-		keys = append(keys, n.Key)
+// clamp limits the expiration time according to TTL limits.
+func (c *Cache[K, V]) clamp(expires time.Time) time.Time {
+	if c.opts.minTTL <= 0 && c.opts.maxTTL <= 0 {
+		return expires
 	}
-	c.m.RUnlock()
 
-	for k := range keys {
-		c.m.RLock()
-		value := c.cache[keys[k]].Value
-		c.m.RUnlock()
+	now := c.now()
+	ttl := expires.Sub(now)
 
-		if !fn(keys[k], value) {
-			break
-		}
+	if ttl < c.opts.minTTL {
+		return now.Add(c.opts.minTTL)
 	}
-}
 
-// Rekey replaces value's key. Returns false if the old key is not present.
-func (c *Cache[K, V]) Rekey(oldKey, newKey K) bool {
-	c.m.Lock()
-
-	item, ok := c.cache[oldKey]
-	if !ok {
-		c.m.Unlock()
-		return false
+	if c.opts.maxTTL > 0 && ttl > c.opts.maxTTL {
+		return now.Add(c.opts.maxTTL)
 	}
 
-	c.cache[newKey] = item
-	delete(c.cache, oldKey)
+	return expires
+}
 
-	c.m.Unlock()
+// override returns expiration time according to TTL overrides, the latest matching override wins.
+func (c *Cache[K, V]) override(key K, expires time.Time) time.Time {
+	for i := len(c.overrides) - 1; i >= 0; i-- {
+		if c.overrides[i].match(key) {
+			return c.now().Add(c.overrides[i].ttl)
+		}
+	}
 
-	return true
+	return expires
 }
 
-// Len returns number of items currently stored in the cache.
-func (c *Cache[K, V]) Len() int {
-	c.m.RLock()
-	defer c.m.RUnlock()
+// deadline returns the time the item is to be removed at, rounding relaxed items up to the sweep interval.
+func (c *Cache[K, V]) deadline(expires time.Time, relaxed bool) time.Time {
+	if !relaxed || c.opts.sweep <= 0 {
+		return expires
+	}
 
-	return len(c.cache)
+	t := expires.Truncate(c.opts.sweep)
+	if t.Before(expires) {
+		t = t.Add(c.opts.sweep)
+	}
+
+	return t
 }
 
+// setTimer re-arms the timer to fire when the head item expires, must be called under the write lock.
 func (c *Cache[K, V]) setTimer() {
-	select {
-	case c.stop <- struct{}{}:
-	default:
+	if c.opts.logger != nil {
+		c.opts.logger.Debug("mcache: expiration timer reset", "key", c.head.Key, "expires", c.head.Expires)
 	}
 
-	go c.ticker(time.NewTimer(time.Until(c.head.Expires)))
+	d := c.head.Expires.Add(c.opts.grace).Sub(c.now())
+
+	if c.timer == nil {
+		c.timer = c.opts.clock.AfterFunc(d, c.ticker)
+	} else {
+		c.timer.Reset(d)
+	}
 }
 
-func (c *Cache[K, V]) ticker(t *time.Timer) {
-	select {
-	case <-t.C:
-	case <-c.stop:
-		t.Stop()
-		return
+// stopTimer stops the timer, must be called under the write lock.
+func (c *Cache[K, V]) stopTimer() {
+	if c.timer != nil {
+		c.timer.Stop()
 	}
+}
 
+// ticker expires all items due within expiryLookAhead in a single pass, so items expiring together
+// do not re-arm the timer one by one. The timer may have fired just before it was re-armed,
+// so the head is checked to be actually expired.
+func (c *Cache[K, V]) ticker() {
 	c.m.Lock()
 
-	if c.head != nil {
-		delete(c.cache, c.head.Key)
+	var items []KeyValue[K, V]
+
+	deadline := c.now().Add(expiryLookAhead)
+
+	for c.head != nil && !deadline.Before(c.head.Expires.Add(c.opts.grace)) {
+		if c.held(c.head) {
+			continue
+		}
+
+		if c.onEvict != nil {
+			items = append(items, KeyValue[K, V]{Key: c.head.Key, Value: c.cache[c.head.Key].Value})
+		}
+
+		if c.opts.logger != nil {
+			c.opts.logger.Debug("mcache: item expired", "key", c.head.Key, "expires", c.head.Expires)
+		}
+
+		c.publishRemoval(EventExpire, c.head.Key)
 
-		c.remove(c.head)
+		c.unlink(c.head.Key, c.head)
+
+		c.stats.expirations.Add(1)
 	}
 
 	if c.head != nil {
 		c.setTimer()
 	}
 
+	logger := c.opts.logger
+
 	c.m.Unlock()
+
+	c.notify(items, Expired, logger)
+}
+
+// notify calls eviction callback for the items, must be called without holding the lock.
+// The logger is the one set when the items were removed, as it may be changed with Reconfigure.
+func (c *Cache[K, V]) notify(items []KeyValue[K, V], reason Reason, logger Logger) {
+	for i := range items {
+		c.callback(items[i].Key, items[i].Value, reason, logger)
+	}
+}
+
+// callback calls eviction callback for the item. With a logger set, the callback panic is logged and recovered.
+func (c *Cache[K, V]) callback(key K, value V, reason Reason, logger Logger) {
+	if logger != nil {
+		defer func() {
+			if r := recover(); r != nil {
+				logger.Debug("mcache: eviction callback panicked", "key", key, "reason", reason, "panic", r)
+			}
+		}()
+	}
+
+	c.onEvict(key, value, reason)
+}
+
+// join returns the computation of the key in progress, or starts a new one, reporting whether the computation is new.
+func (c *Cache[K, V]) join(key K) (*flight[V], bool) {
+	c.fm.Lock()
+	defer c.fm.Unlock()
+
+	if f, ok := c.flights[key]; ok {
+		return f, false
+	}
+
+	if c.flights == nil {
+		c.flights = make(map[K]*flight[V])
+	}
+
+	f := &flight[V]{
+		done: make(chan struct{}),
+		err:  ErrComputePanicked, // Replaced unless the computation panics
+	}
+
+	c.flights[key] = f
+
+	return f, true
+}
+
+// land finishes the computation of the key, releasing the waiting callers.
+func (c *Cache[K, V]) land(key K, f *flight[V]) {
+	c.fm.Lock()
+	delete(c.flights, key)
+	c.fm.Unlock()
+
+	close(f.done)
+}
+
+// alive reports whether the item has not expired yet. Without grace period expired items are removed right away.
+// No items are alive while the cache is disabled.
+func (c *Cache[K, V]) alive(i *item[K]) bool {
+	return !c.disabled.Load() && (c.opts.grace == 0 || c.now().Before(i.Expires))
+}
+
+// drop deletes the key, re-arming the timer if the earliest item was deleted.
+func (c *Cache[K, V]) drop(key K) bool {
+	timerResetNeeded := c.head != nil && c.head.Key == key
+
+	ok := c.delete(key)
+
+	if c.head != nil && timerResetNeeded {
+		c.setTimer()
+	}
+
+	return ok
 }
 
 func (c *Cache[K, V]) delete(key K) bool {
@@ -354,11 +1918,43 @@ func (c *Cache[K, V]) delete(key K) bool {
 		return false
 	}
 
-	c.remove(value.Ptr)
+	c.unlink(key, value.Ptr)
+
+	return true
+}
+
+// unlink removes the item from the queue and the key from the map.
+func (c *Cache[K, V]) unlink(key K, n *item[K]) {
+	if l := len(c.cache); l > c.peak {
+		c.peak = l
+	}
+
+	c.remove(n)
+
+	if v, ok := c.cache[key]; ok {
+		c.size -= c.entrySize(key, v.Value)
+		delete(c.costs, key)
+	}
 
 	delete(c.cache, key)
+	c.unindex(key)
 
-	return true
+	c.shrink()
+}
+
+// shrink rebuilds the map to release memory once most of its peak items were removed.
+func (c *Cache[K, V]) shrink() {
+	if c.peak < shrinkMinPeak || len(c.cache) > c.peak/shrinkFactor {
+		return
+	}
+
+	m := make(map[K]valuePtr[K, V], len(c.cache))
+
+	for key, v := range c.cache {
+		m[key] = v
+	}
+
+	c.cache, c.peak = m, len(m)
 }
 
 func (c *Cache[K, V]) remove(n *item[K]) (r *item[K]) {
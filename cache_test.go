@@ -1,6 +1,12 @@
 package mcache_test
 
 import (
+	"context"
+	"errors"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -190,6 +196,14 @@ func TestRefresh(t *testing.T) {
 	assert.Eventually(t, func() bool {
 		return 0 == c.Len()
 	}, 3500*time.Millisecond, 20*time.Millisecond)
+
+	c.Set(1, 1, time.Second)
+
+	assert.True(t, c.Refresh(1, 10*time.Millisecond))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
 }
 
 func TestUpdate(t *testing.T) {
@@ -199,6 +213,9 @@ func TestUpdate(t *testing.T) {
 	assert.True(t, c.Update("a", "bar"))
 	assert.False(t, c.Update("x", "bar"))
 
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, "bar", v)
+	}
 }
 
 func TestLargeCache(t *testing.T) {
@@ -248,6 +265,67 @@ func TestSwap(t *testing.T) {
 	}, 150*time.Millisecond, 5*time.Millisecond)
 }
 
+func TestSwapWithTTL(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 100, 10*time.Millisecond)
+	c.Set(2, 200, 20*time.Millisecond)
+
+	if v, ok := c.SwapWithTTL(1, 111, 30*time.Millisecond); assert.True(t, ok) {
+		assert.Equal(t, 100, v)
+	}
+
+	require.Equal(t, []int{2, 1}, c.Keys())
+
+	ttl, ok := c.TTL(1)
+	require.True(t, ok)
+	require.Greater(t, ttl, 20*time.Millisecond)
+
+	_, ok = c.SwapWithTTL(3, 3, time.Millisecond)
+	require.False(t, ok)
+	require.False(t, c.Has(3))
+
+	time.Sleep(15 * time.Millisecond)
+
+	v, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, 111, v)
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 5*time.Millisecond)
+}
+
+func TestSetGet(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	_, ok := c.SetGet("a", 1, 10*time.Millisecond)
+	require.False(t, ok)
+
+	old, ok := c.SetGet("a", 2, 10*time.Millisecond)
+	require.True(t, ok)
+	require.Equal(t, 1, old)
+
+	v, ok := c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 2, v)
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 5*time.Millisecond)
+}
+
+func TestGetOrDefault(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 10*time.Millisecond)
+
+	require.Equal(t, 1, c.GetOrDefault("a", -1))
+	require.Equal(t, -1, c.GetOrDefault("b", -1))
+
+	c.Clear()
+}
+
 func TestEvict(t *testing.T) {
 	c := mcache.New[int, int]()
 
@@ -321,48 +399,1285 @@ func TestGetMany(t *testing.T) {
 	require.Equal(t, map[int]string{1: "1", 3: "3", 5: "5"}, c.GetMany(5, 3, 1))
 }
 
-func BenchmarkCacheSet(b *testing.B) {
+func TestGetStale(t *testing.T) {
+	c := mcache.New[int, int](mcache.WithGracePeriod(100 * time.Millisecond))
+
+	c.Set(1, 1, 20*time.Millisecond)
+
+	v, ok, expired := c.GetStale(1)
+	require.True(t, ok)
+	require.False(t, expired)
+	require.Equal(t, 1, v)
+
+	time.Sleep(30 * time.Millisecond)
+
+	_, ok = c.Get(1)
+	require.False(t, ok)
+
+	v, ok, expired = c.GetStale(1)
+	require.True(t, ok)
+	require.True(t, expired)
+	require.Equal(t, 1, v)
+
+	assert.Eventually(t, func() bool {
+		_, ok, _ := c.GetStale(1)
+		return !ok
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestGetManyBudget(t *testing.T) {
 	c := mcache.New[int, int]()
 
-	b.ReportAllocs()
-	b.ResetTimer()
+	keys := make([]int, 5000)
+	for i := range keys {
+		keys[i] = i
+		c.Set(i, i, 100*time.Millisecond)
+	}
 
-	for i := 0; i < b.N; i++ {
-		c.Set(i, i, 50*time.Millisecond)
+	values, n := c.GetManyBudget(0, keys...)
+	require.Equal(t, len(keys), n)
+	require.Len(t, values, len(keys))
+
+	values, n = c.GetManyBudget(time.Nanosecond, keys...)
+	require.Equal(t, 1024, n)
+	require.Len(t, values, n)
+
+	rest, m := c.GetManyBudget(0, keys[n:]...)
+	require.Equal(t, len(keys)-n, m)
+	require.Len(t, rest, len(keys)-n)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 300*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestGetManyLookups(t *testing.T) {
+	c := mcache.New[string, int](
+		mcache.WithSlidingTTL(),
+		mcache.WithHotKeyTracking(time.Second, 1),
+	)
+
+	c.Set("a", 1, 40*time.Millisecond)
+
+	values := c.GetMany("a", "b", "b", "b", "a")
+	require.Equal(t, map[string]int{"a": 1}, values)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(2), stats.Hits)
+	require.Equal(t, uint64(3), stats.Misses)
+
+	require.Equal(t, []string{"b"}, c.TopKeys(1))
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+
+		require.Len(t, c.GetMany("a"), 1)
 	}
 }
 
-func BenchmarkCacheGet(b *testing.B) {
-	c := mcache.New[int, int]()
+func TestGetOrSet(t *testing.T) {
+	c := mcache.New[string, int]()
 
-	c.Set(1, 1, 5*time.Second)
+	v, ok := c.GetOrSet("a", 1, 50*time.Millisecond)
+	require.False(t, ok)
+	require.Equal(t, 1, v)
 
-	b.ReportAllocs()
-	b.ResetTimer()
+	v, ok = c.GetOrSet("a", 2, 50*time.Millisecond)
+	require.True(t, ok)
+	require.Equal(t, 1, v)
 
-	for i := 0; i < b.N; i++ {
-		if n, ok := c.Get(1); !(ok && n == 1) {
-			b.Fail()
-		}
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestPrecision(t *testing.T) {
+	c := mcache.New[string, int](
+		mcache.WithPrecision(mcache.Relaxed),
+		mcache.WithSweepInterval(200*time.Millisecond),
+	)
+
+	c.Set("relaxed", 1, 10*time.Millisecond)
+	c.SetWithPrecision("precise", 2, 10*time.Millisecond, mcache.Precise)
+
+	assert.Eventually(t, func() bool {
+		_, ok := c.Get("precise")
+		return !ok
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 300*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestGetOrCompute(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	var (
+		calls int32
+		wg    sync.WaitGroup
+	)
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			v, err := c.GetOrCompute("a", func() (int, time.Duration, error) {
+				atomic.AddInt32(&calls, 1)
+				time.Sleep(10 * time.Millisecond)
+
+				return 42, 50 * time.Millisecond, nil
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, 42, v)
+		}()
 	}
 
-	b.StopTimer()
-	c.Evict(1)
+	wg.Wait()
+
+	require.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	_, err := c.GetOrCompute("b", func() (int, time.Duration, error) {
+		return 0, 0, errors.New("failed")
+	})
+	require.EqualError(t, err, "failed")
+
+	_, ok := c.Get("b")
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
 }
 
-func BenchmarkCacheAddGet(b *testing.B) {
+func TestReconfigure(t *testing.T) {
 	c := mcache.New[int, int]()
 
-	b.ReportAllocs()
-	b.ResetTimer()
+	c.Reconfigure(mcache.WithGracePeriod(time.Second))
 
-	for i := 0; i < b.N; i++ {
-		c.Set(i, i, 50*time.Millisecond)
+	c.Set(1, 1, 10*time.Millisecond)
 
-		if n, ok := c.Get(i); !(ok && n == i) {
-			b.Fail()
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok, expired := c.GetStale(1)
+	require.True(t, ok)
+	require.True(t, expired)
+
+	c.Reconfigure(mcache.WithGracePeriod(0))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestReconfigureLoggerBudget(t *testing.T) {
+	var evicted []int
+
+	c := mcache.New[int, string](mcache.WithEvictionCallback(func(key int, _ string, reason mcache.Reason) {
+		if reason == mcache.Evicted {
+			evicted = append(evicted, key)
 		}
+	}))
 
-		c.Delete(i)
+	for i := 0; i < 10; i++ {
+		c.Set(i, "value", time.Minute+time.Duration(i)*time.Second)
+	}
+
+	l := &testLogger{}
+
+	var wg sync.WaitGroup
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		c.Sweep()
+		c.Evict(0)
+	}()
+
+	c.Reconfigure(mcache.WithLogger(l))
+
+	wg.Wait()
+
+	require.Equal(t, 1, c.Evict(1))
+	require.True(t, l.Has("mcache: items evicted count 1"))
+
+	// Shrinking the budget evicts the earliest expiring items right away
+	c.Reconfigure(mcache.WithMemoryBudget[int, string](1, nil))
+
+	require.Zero(t, c.Len())
+	require.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, evicted)
+	require.True(t, l.Has("mcache: items evicted over memory budget count 9"))
+}
+
+func TestSetIfAbsent(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	require.True(t, c.SetIfAbsent("a", 1, 50*time.Millisecond))
+	require.False(t, c.SetIfAbsent("a", 2, 50*time.Millisecond))
+
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, 1, v)
+	}
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestGetWithFallback(t *testing.T) {
+	c := mcache.New[string, string](mcache.WithDefaultTTL(50 * time.Millisecond))
+
+	missing := func(string) (string, bool) { return "", false }
+	config := func(key string) (string, bool) { return key + " from config", true }
+
+	v, ok := c.GetWithFallback("a", missing, config)
+	require.True(t, ok)
+	require.Equal(t, "a from config", v)
+
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, "a from config", v)
+	}
+
+	_, ok = c.GetWithFallback("b", missing)
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestCompareAndSwap(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 50*time.Millisecond)
+
+	require.False(t, c.CompareAndSwap("a", 2, 3))
+	require.True(t, c.CompareAndSwap("a", 1, 3))
+	require.False(t, c.CompareAndSwap("b", 1, 3))
+
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, 3, v)
+	}
+
+	s := mcache.New[string, []int](mcache.WithEqual(func(a, b []int) bool {
+		return len(a) == len(b)
+	}))
+
+	s.Set("a", []int{1}, 50*time.Millisecond)
+
+	require.True(t, s.CompareAndSwap("a", []int{2}, []int{3, 4}))
+
+	require.Panics(t, func() {
+		mcache.New[string, int](mcache.WithEqual(func(a, b string) bool { return a == b }))
+	})
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+s.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestCompareAndDelete(t *testing.T) {
+	c := mcache.New[string, string]()
+
+	c.Set("lock", "owner-1", 50*time.Millisecond)
+
+	require.False(t, c.CompareAndDelete("lock", "owner-2"))
+	require.True(t, c.CompareAndDelete("lock", "owner-1"))
+	require.False(t, c.CompareAndDelete("lock", "owner-1"))
+
+	require.Zero(t, c.Len())
+}
+
+func TestCompute(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	inc := func(value int, _ bool) (int, time.Duration, bool) {
+		return value + 1, 50 * time.Millisecond, false
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			c.Compute("a", inc)
+		}()
+	}
+
+	wg.Wait()
+
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, 100, v)
+	}
+
+	_, ok := c.Compute("a", func(int, bool) (int, time.Duration, bool) {
+		return 0, 0, true
+	})
+	require.False(t, ok)
+	require.Zero(t, c.Len())
+}
+
+func TestComputeIfPresent(t *testing.T) {
+	c := mcache.New[string, []string]()
+
+	c.Set("a", []string{"foo"}, 50*time.Millisecond)
+
+	require.True(t, c.ComputeIfPresent("a", func(v []string) []string {
+		return append(v, "bar")
+	}))
+
+	require.False(t, c.ComputeIfPresent("b", func(v []string) []string {
+		return v
+	}))
+
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, []string{"foo", "bar"}, v)
+	}
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestDisable(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 50*time.Millisecond)
+	c.Set("b", 2, 50*time.Millisecond)
+
+	c.Disable()
+	require.True(t, c.Disabled())
+
+	_, ok := c.Get("a")
+	require.False(t, ok)
+
+	c.Set("b", 3, 50*time.Millisecond)
+	c.Set("c", 3, 50*time.Millisecond)
+	require.Equal(t, 1, c.Len())
+
+	c.Enable()
+
+	if v, ok := c.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, 1, v)
 	}
+
+	_, ok = c.Get("b")
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestOverrideTTL(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("session:1", 1, time.Hour)
+	c.Set("session:2", 2, time.Hour)
+	c.Set("page:1", 3, 50*time.Millisecond)
+
+	isSession := func(key string) bool { return strings.HasPrefix(key, "session:") }
+
+	require.Equal(t, 2, c.OverrideTTL(isSession, 20*time.Millisecond, true))
+
+	c.Set("session:3", 4, time.Hour)
+
+	assert.Eventually(t, func() bool {
+		return 1 == c.Len()
+	}, 40*time.Millisecond, 5*time.Millisecond)
+
+	c.ClearTTLOverrides()
+
+	c.Set("session:4", 5, 20*time.Millisecond)
+	require.Equal(t, 1, c.OverrideTTL(isSession, 10*time.Millisecond, false))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestMGet(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	c.Set(1, "1", 50*time.Millisecond)
+	c.Set(3, "3", 50*time.Millisecond)
+
+	values, found := c.MGet(3, 2, 1)
+	require.Equal(t, []string{"3", "", "1"}, values)
+	require.Equal(t, []bool{true, false, true}, found)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestKeysValues(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	c.Set(2, "2", 60*time.Millisecond)
+	c.Set(1, "1", 50*time.Millisecond)
+	c.Set(3, "3", 70*time.Millisecond)
+
+	require.Equal(t, []int{1, 2, 3}, c.Keys())
+	require.Equal(t, []string{"1", "2", "3"}, c.Values())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestItems(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	start := time.Now()
+
+	c.Set(1, "1", 50*time.Millisecond)
+	c.Set(2, "2", 60*time.Millisecond)
+
+	items := c.Items()
+	require.Len(t, items, 2)
+	require.Equal(t, "1", items[1].Value)
+	require.WithinDuration(t, start.Add(60*time.Millisecond), items[2].Expires, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestClear(t *testing.T) {
+	var (
+		m       sync.Mutex
+		reasons = map[int]mcache.Reason{}
+	)
+
+	c := mcache.New[int, int](mcache.WithEvictionCallback(func(key, _ int, reason mcache.Reason) {
+		m.Lock()
+		reasons[key] = reason
+		m.Unlock()
+	}))
+
+	c.Set(1, 1, 10*time.Millisecond)
+	c.Set(2, 2, 50*time.Millisecond)
+	c.Set(3, 3, 60*time.Millisecond)
+	c.Set(4, 4, 70*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 3 == c.Len()
+	}, 40*time.Millisecond, 5*time.Millisecond)
+
+	require.Equal(t, 1, c.Evict(1))
+
+	c.Clear()
+
+	require.Zero(t, c.Len())
+
+	m.Lock()
+	require.Equal(t, map[int]mcache.Reason{
+		1: mcache.Expired,
+		2: mcache.Evicted,
+		3: mcache.Cleared,
+		4: mcache.Cleared,
+	}, reasons)
+	m.Unlock()
+
+	c.Set(5, 5, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 50*time.Millisecond, 5*time.Millisecond)
+
+	require.Panics(t, func() {
+		mcache.New[int, string](mcache.WithEvictionCallback(func(int, int, mcache.Reason) {}))
+	})
+}
+
+func TestClearAsync(t *testing.T) {
+	var cleared atomic.Int32
+
+	c := mcache.New[int, int](mcache.WithEvictionCallback(func(_, _ int, reason mcache.Reason) {
+		if reason == mcache.Cleared {
+			cleared.Add(1)
+		}
+	}))
+
+	c.SetMany(map[int]int{1: 1, 2: 2, 3: 3}, 50*time.Millisecond)
+
+	done := c.ClearAsync()
+
+	require.Zero(t, c.Len())
+
+	<-done
+
+	require.Equal(t, int32(3), cleared.Load())
+
+	<-mcache.New[int, int]().ClearAsync()
+}
+
+func TestHas(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 50*time.Millisecond)
+
+	require.True(t, c.Has("a"))
+	require.False(t, c.Has("b"))
+
+	assert.Eventually(t, func() bool {
+		return !c.Has("a")
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestGetWithExpiry(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	start := time.Now()
+
+	c.Set("a", 1, 50*time.Millisecond)
+
+	v, expires, ok := c.GetWithExpiry("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	require.WithinDuration(t, start.Add(50*time.Millisecond), expires, 10*time.Millisecond)
+
+	_, expires, ok = c.GetWithExpiry("b")
+	require.False(t, ok)
+	require.Zero(t, expires)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestPeek(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithEntryInfo())
+
+	c.Set("a", 1, time.Minute)
+
+	_, want, _ := c.GetWithExpiry("a")
+
+	v, expires, ok := c.Peek("a")
+	require.True(t, ok)
+	require.Equal(t, 1, v)
+	require.Equal(t, want, expires)
+
+	_, _, ok = c.Peek("b")
+	require.False(t, ok)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(0), stats.Misses)
+
+	info, _ := c.GetEntryInfo("a")
+	require.Equal(t, uint64(1), info.Hits)
+}
+
+func TestTTL(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 50*time.Millisecond)
+
+	ttl, ok := c.TTL("a")
+	require.True(t, ok)
+	require.InDelta(t, 50*time.Millisecond, ttl, float64(10*time.Millisecond))
+
+	_, ok = c.TTL("b")
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestSetUntil(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	deadline := time.Now().Add(30 * time.Millisecond)
+
+	c.SetUntil("a", 1, deadline)
+
+	_, expires, ok := c.GetWithExpiry("a")
+	require.True(t, ok)
+	require.True(t, deadline.Equal(expires))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestExtend(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 50*time.Millisecond)
+	c.Set("b", 2, 40*time.Millisecond)
+
+	_, before, _ := c.GetWithExpiry("a")
+
+	require.True(t, c.Extend("a", 20*time.Millisecond))
+	require.True(t, c.Extend("b", -30*time.Millisecond))
+	require.False(t, c.Extend("c", time.Millisecond))
+
+	_, after, _ := c.GetWithExpiry("a")
+	require.Equal(t, 20*time.Millisecond, after.Sub(before))
+
+	require.Equal(t, []string{"b", "a"}, c.Keys())
+
+	assert.Eventually(t, func() bool {
+		return 1 == c.Len()
+	}, 30*time.Millisecond, 2*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestTouch(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithDefaultTTL(100 * time.Millisecond))
+
+	c.Set("a", 1, 10*time.Millisecond)
+
+	require.True(t, c.Touch("a"))
+	require.False(t, c.Touch("b"))
+
+	ttl, ok := c.TTL("a")
+	require.True(t, ok)
+	require.Greater(t, ttl, 50*time.Millisecond)
+
+	require.False(t, mcache.New[string, int]().Touch("a"))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestSlidingTTL(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithSlidingTTL())
+
+	c.Set("a", 1, 40*time.Millisecond)
+
+	for i := 0; i < 5; i++ {
+		time.Sleep(20 * time.Millisecond)
+
+		_, ok := c.Get("a")
+		require.True(t, ok)
+	}
+
+	_, ok := c.Get("b")
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestTTLJitter(t *testing.T) {
+	c := mcache.New[int, int](mcache.WithTTLJitter(0.5))
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, 100*time.Millisecond)
+	}
+
+	var lower, higher bool
+
+	for _, item := range c.Items() {
+		ttl := time.Until(item.Expires)
+		require.InDelta(t, 100*time.Millisecond, ttl, float64(55*time.Millisecond))
+
+		lower = lower || ttl < 90*time.Millisecond
+		higher = higher || ttl > 100*time.Millisecond
+	}
+
+	require.True(t, lower)
+	require.True(t, higher)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestTTLJitterLimits(t *testing.T) {
+	c := mcache.New[int, int](
+		mcache.WithTTLJitter(0.5),
+		mcache.WithTTLLimits(0, 100*time.Millisecond),
+	)
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, 100*time.Millisecond)
+	}
+
+	for _, item := range c.Items() {
+		require.LessOrEqual(t, time.Until(item.Expires), 100*time.Millisecond)
+	}
+}
+
+func TestSetUntilAbsolute(t *testing.T) {
+	c := mcache.New[string, int](
+		mcache.WithTTLJitter(0.5),
+		mcache.WithTTLLimits(time.Minute, 2*time.Minute),
+	)
+
+	deadline := time.Now().Add(time.Hour)
+
+	c.SetUntil("a", 1, deadline)
+
+	_, expires, ok := c.GetWithExpiry("a")
+	require.True(t, ok)
+	require.True(t, deadline.Equal(expires))
+}
+
+func TestTTLLimits(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithTTLLimits(20*time.Millisecond, 50*time.Millisecond))
+
+	c.Set("short", 1, time.Nanosecond)
+	c.Set("long", 2, time.Hour)
+
+	ttl, ok := c.TTL("short")
+	require.True(t, ok)
+	require.Greater(t, ttl, 10*time.Millisecond)
+
+	ttl, ok = c.TTL("long")
+	require.True(t, ok)
+	require.LessOrEqual(t, ttl, 50*time.Millisecond)
+
+	require.True(t, c.Refresh("short", time.Hour))
+
+	ttl, _ = c.TTL("short")
+	require.LessOrEqual(t, ttl, 50*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestTouchTTLLimits(t *testing.T) {
+	long := mcache.New[string, int](
+		mcache.WithDefaultTTL(time.Hour),
+		mcache.WithTTLLimits(0, 50*time.Millisecond),
+	)
+
+	long.Set("a", 1, 10*time.Millisecond)
+	require.True(t, long.Touch("a"))
+
+	ttl, ok := long.TTL("a")
+	require.True(t, ok)
+	require.LessOrEqual(t, ttl, 50*time.Millisecond)
+
+	short := mcache.New[string, int](
+		mcache.WithDefaultTTL(time.Nanosecond),
+		mcache.WithTTLLimits(20*time.Millisecond, 0),
+	)
+
+	short.Set("a", 1, 30*time.Millisecond)
+	require.True(t, short.Touch("a"))
+
+	ttl, ok = short.TTL("a")
+	require.True(t, ok)
+	require.Greater(t, ttl, 10*time.Millisecond)
+	require.LessOrEqual(t, ttl, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == long.Len()+short.Len()
+	}, 150*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestExtendTTLLimits(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithTTLLimits(20*time.Millisecond, 50*time.Millisecond))
+
+	c.Set("a", 1, 40*time.Millisecond)
+	c.Set("b", 2, 40*time.Millisecond)
+
+	require.True(t, c.Extend("a", time.Hour))
+	require.True(t, c.Extend("b", -time.Hour))
+
+	ttl, ok := c.TTL("a")
+	require.True(t, ok)
+	require.LessOrEqual(t, ttl, 50*time.Millisecond)
+
+	ttl, ok = c.TTL("b")
+	require.True(t, ok)
+	require.Greater(t, ttl, 10*time.Millisecond)
+	require.LessOrEqual(t, ttl, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 150*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestPop(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	c.Set(2, "2", 20*time.Millisecond)
+	c.Set(1, "1", 10*time.Millisecond)
+	c.Set(3, "3", 30*time.Millisecond)
+
+	k, v, ok := c.PopOldest()
+	require.True(t, ok)
+	require.Equal(t, 1, k)
+	require.Equal(t, "1", v)
+
+	k, v, ok = c.PopNewest()
+	require.True(t, ok)
+	require.Equal(t, 3, k)
+	require.Equal(t, "3", v)
+
+	k, _, ok = c.PopNewest()
+	require.True(t, ok)
+	require.Equal(t, 2, k)
+
+	_, _, ok = c.PopOldest()
+	require.False(t, ok)
+
+	_, _, ok = c.PopNewest()
+	require.False(t, ok)
+}
+
+func TestPeekOldest(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	_, _, _, ok := c.PeekOldest()
+	require.False(t, ok)
+
+	_, ok = c.NextExpiration()
+	require.False(t, ok)
+
+	c.Set(2, "2", 20*time.Millisecond)
+	c.Set(1, "1", 10*time.Millisecond)
+
+	k, v, expires, ok := c.PeekOldest()
+	require.True(t, ok)
+	require.Equal(t, 1, k)
+	require.Equal(t, "1", v)
+
+	next, ok := c.NextExpiration()
+	require.True(t, ok)
+	require.Equal(t, expires, next)
+
+	require.Equal(t, 2, c.Len())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestSweep(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	require.Zero(t, c.Sweep())
+
+	c.SetUntil(1, 1, time.Now().Add(-time.Second))
+	c.SetUntil(2, 2, time.Now().Add(-time.Second))
+	c.Set(3, 3, 20*time.Millisecond)
+
+	// The timer could have removed some of the expired items already
+	require.LessOrEqual(t, c.Sweep(), 2)
+	require.Equal(t, 1, c.Len())
+	require.Zero(t, c.Sweep())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestRangeWithExpiry(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(2, 2, 40*time.Millisecond)
+	c.Set(1, 1, 30*time.Millisecond)
+
+	var (
+		seen []int
+		last time.Time
+	)
+
+	c.RangeWithExpiry(func(k, v int, expires time.Time) bool {
+		assert.Equal(t, k, v)
+		assert.True(t, expires.After(last))
+		seen = append(seen, k)
+		last = expires
+		return true
+	})
+
+	require.Equal(t, []int{1, 2}, seen)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestRangeReverse(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 1, 30*time.Millisecond)
+	c.Set(2, 2, 40*time.Millisecond)
+	c.Set(3, 3, 50*time.Millisecond)
+
+	var seen []int
+
+	c.RangeReverse(func(k, _ int) bool {
+		seen = append(seen, k)
+		return k != 2
+	})
+
+	require.Equal(t, []int{3, 2}, seen)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestExpiringWithin(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 1, 10*time.Millisecond)
+	c.Set(2, 2, 20*time.Millisecond)
+	c.Set(3, 3, 100*time.Millisecond)
+
+	var seen []int
+
+	c.ExpiringWithin(50*time.Millisecond, func(k, _ int, _ time.Time) bool {
+		seen = append(seen, k)
+		return true
+	})
+
+	require.Equal(t, []int{1, 2}, seen)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestFilter(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 1, 30*time.Millisecond)
+	c.Set(2, 2, 40*time.Millisecond)
+	c.Set(3, 3, 50*time.Millisecond)
+
+	f := c.Filter(func(k, _ int) bool {
+		return k != 2
+	})
+
+	require.Equal(t, []int{1, 3}, f.Keys())
+	require.Equal(t, 3, c.Len())
+
+	_, expected, _ := c.GetWithExpiry(3)
+	_, expires, _ := f.GetWithExpiry(3)
+	require.Equal(t, expected, expires)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+f.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestClone(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 1, 30*time.Millisecond)
+	c.Set(2, 2, 40*time.Millisecond)
+
+	clone := c.Clone()
+
+	c.Delete(1)
+	clone.Set(3, 3, 50*time.Millisecond)
+
+	require.Equal(t, []int{2}, c.Keys())
+	require.Equal(t, []int{1, 2, 3}, clone.Keys())
+
+	_, expected, _ := c.GetWithExpiry(2)
+	_, expires, _ := clone.GetWithExpiry(2)
+	require.Equal(t, expected, expires)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+clone.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestCloneOptions(t *testing.T) {
+	var loads atomic.Int32
+
+	c := mcache.New[int, int](
+		mcache.WithEvents(10),
+		mcache.WithHotKeyTracking(time.Minute, 3),
+		mcache.WithGracePeriod(time.Minute),
+		mcache.WithNegativeTTL(time.Minute),
+		mcache.WithLoader(func(context.Context, int) (int, time.Duration, error) {
+			loads.Add(1)
+
+			return 0, 0, mcache.ErrNotFound
+		}),
+	)
+
+	c.Set(1, 1, time.Millisecond)
+	c.Set(2, 2, time.Minute)
+
+	time.Sleep(5 * time.Millisecond)
+
+	_, ok, expired := c.GetStale(1)
+	require.True(t, ok)
+	require.True(t, expired)
+
+	clone := c.Clone()
+
+	// Expired items kept for the grace period are not copied
+	require.Equal(t, []int{2}, clone.Keys())
+
+	require.NotNil(t, clone.Events())
+
+	clone.Set(3, 3, time.Minute)
+	require.Equal(t, mcache.Event[int, int]{Type: mcache.EventSet, Key: 3, Value: 3}, <-clone.Events())
+
+	clone.Get(2)
+	require.Equal(t, []int{2}, clone.TopKeys(1))
+
+	_, ok = clone.Get(4)
+	require.False(t, ok)
+	_, ok = clone.Get(4)
+	require.False(t, ok)
+	require.EqualValues(t, 1, loads.Load())
+}
+
+func TestNewFromMap(t *testing.T) {
+	c := mcache.NewFromMap(map[int]string{1: "one", 2: "two"}, 30*time.Millisecond)
+
+	require.Equal(t, 2, c.Len())
+
+	v, ok := c.Get(2)
+	require.True(t, ok)
+	require.Equal(t, "two", v)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestTimerReuse(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	before := runtime.NumGoroutine()
+
+	// Every item becomes the new head, re-arming the timer
+	for i := 1000; i > 0; i-- {
+		c.Set(i, i, time.Duration(i)*time.Millisecond+time.Minute)
+	}
+
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before+1)
+
+	assert.Equal(t, 1000, c.Evict(c.Len()))
+	assert.Zero(t, c.Len())
+}
+
+func TestCacheShrink(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	for i := 0; i < 10000; i++ {
+		c.Set(i, i, time.Minute)
+	}
+
+	keys := make([]int, 0, 9000)
+	for i := 0; i < 9000; i++ {
+		keys = append(keys, i)
+	}
+
+	// The map is rebuilt while deleting
+	require.Equal(t, 9000, c.DeleteMany(keys...))
+	require.Equal(t, 1000, c.Len())
+
+	for i := 9000; i < 10000; i++ {
+		value, ok := c.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, value)
+	}
+
+	assert.Equal(t, 1000, c.Evict(c.Len()))
+	assert.Zero(t, c.Len())
+}
+
+func TestBatchExpiry(t *testing.T) {
+	l := &testLogger{}
+	c := mcache.New[int, int](mcache.WithLogger(l))
+
+	expires := time.Now().Add(20 * time.Millisecond)
+
+	for i := 0; i < 1000; i++ {
+		c.SetUntil(i, i, expires)
+	}
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	assert.Equal(t, uint64(1000), c.Stats().Expirations)
+
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	resets := 0
+
+	for _, event := range l.events {
+		if strings.HasPrefix(event, "mcache: expiration timer reset") {
+			resets++
+		}
+	}
+
+	// Set once for the first item, all items expire in a single pass
+	assert.Equal(t, 1, resets)
+}
+
+func TestGetOrComputeSharedResult(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	var (
+		calls   int32
+		wg      sync.WaitGroup
+		failed  = errors.New("failed")
+		started = make(chan struct{})
+		release = make(chan struct{})
+	)
+
+	go func() {
+		_, err := c.GetOrCompute("a", func() (int, time.Duration, error) {
+			atomic.AddInt32(&calls, 1)
+			close(started)
+			<-release
+
+			return 0, 0, failed
+		})
+
+		assert.ErrorIs(t, err, failed)
+	}()
+
+	<-started
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			_, err := c.GetOrCompute("a", func() (int, time.Duration, error) {
+				atomic.AddInt32(&calls, 1)
+
+				return 1, 10 * time.Millisecond, nil
+			})
+
+			// Callers coming after the failed computation compute the value again
+			if err == nil {
+				return
+			}
+
+			assert.ErrorIs(t, err, failed)
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.LessOrEqual(t, atomic.LoadInt32(&calls), int32(2))
+
+	release = make(chan struct{})
+	waiting := make(chan error)
+
+	go func() {
+		defer func() {
+			assert.NotNil(t, recover())
+		}()
+
+		_, _ = c.GetOrCompute("b", func() (int, time.Duration, error) {
+			<-release
+
+			panic("boom")
+		})
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+
+	go func() {
+		_, err := c.GetOrCompute("b", func() (int, time.Duration, error) {
+			return 1, time.Millisecond, nil
+		})
+
+		waiting <- err
+	}()
+
+	time.Sleep(5 * time.Millisecond)
+	close(release)
+
+	require.ErrorIs(t, <-waiting, mcache.ErrComputePanicked)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func BenchmarkCacheSet(b *testing.B) {
+	c := mcache.New[int, int]()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		c.Set(i, i, 50*time.Millisecond)
+	}
+}
+
+func BenchmarkCacheGet(b *testing.B) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 1, 5*time.Second)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if n, ok := c.Get(1); !(ok && n == 1) {
+			b.Fail()
+		}
+	}
+
+	b.StopTimer()
+	c.Evict(1)
+}
+
+func BenchmarkCacheAddGet(b *testing.B) {
+	c := mcache.New[int, int]()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		c.Set(i, i, 50*time.Millisecond)
+
+		if n, ok := c.Get(i); !(ok && n == i) {
+			b.Fail()
+		}
+
+		c.Delete(i)
+	}
+}
+
+func BenchmarkCacheGetUint64(b *testing.B) {
+	c := mcache.New[uint64, uint64]()
+
+	for i := uint64(0); i < 1000; i++ {
+		c.Set(i, i, 5*time.Second)
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if n, ok := c.Get(uint64(i % 1000)); !(ok && n == uint64(i%1000)) {
+			b.Fail()
+		}
+	}
+
+	b.StopTimer()
+	c.Evict(1000)
 }
@@ -0,0 +1,20 @@
+package mcache
+
+import "time"
+
+// Cacher is the basic set of cache operations, implemented by Cache and its wrappers.
+type Cacher[K comparable, V any] interface {
+	Set(key K, value V, ttl time.Duration)
+	Get(key K) (V, bool)
+	Delete(key K) bool
+	Refresh(key K, ttl time.Duration) bool
+	Len() int
+}
+
+var (
+	_ Cacher[int, int]    = (*Cache[int, int])(nil)
+	_ Cacher[int, int]    = (*Shadow[int, int])(nil)
+	_ Cacher[int, int]    = (*AppendLog[int, int])(nil)
+	_ Cacher[int, string] = (*Compressed[int, string])(nil)
+	_ Cacher[int, int]    = (*Tiered[int, int])(nil)
+)
@@ -0,0 +1,64 @@
+package mcache
+
+import "time"
+
+// Clock is the source of time for the cache, replaced with WithClock.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc calls f once the duration elapses. The cache passes f acquiring the cache lock,
+	// so f must not be called synchronously from AfterFunc or Timer.Reset.
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a timer created by Clock.AfterFunc.
+type Timer interface {
+	Reset(d time.Duration) bool
+	Stop() bool
+}
+
+// systemClock is the Clock backed by the time package
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// now returns the current time of the cache clock.
+func (c *Cache[K, V]) now() time.Time {
+	return c.opts.clock.Now()
+}
+
+// clockTicker delivers ticks every interval of the clock, the next interval starts once the tick is received
+type clockTicker struct {
+	C        <-chan struct{}
+	timer    Timer
+	interval time.Duration
+}
+
+func newClockTicker(clock Clock, interval time.Duration) *clockTicker {
+	c := make(chan struct{}, 1)
+
+	return &clockTicker{
+		C: c,
+		timer: clock.AfterFunc(interval, func() {
+			select {
+			case c <- struct{}{}:
+			default:
+			}
+		}),
+		interval: interval,
+	}
+}
+
+// next starts the next interval, must be called after receiving the tick.
+func (t *clockTicker) next() {
+	t.timer.Reset(t.interval)
+}
+
+func (t *clockTicker) stop() {
+	t.timer.Stop()
+}
@@ -0,0 +1,141 @@
+/*
+Package clockmock provides a fake clock for testing code using mcache.
+
+Time of the clock only moves with Advance and Set, which call functions of due timers synchronously,
+so the cache expires items before they return:
+
+	clock := clockmock.New(time.Now())
+	c := mcache.New[string, int](mcache.WithClock(clock))
+
+	c.Set("key", 1, time.Minute)
+	clock.Advance(time.Minute)
+
+	_, ok := c.Get("key") // ok is false
+*/
+package clockmock
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+)
+
+// Clock is a fake mcache.Clock, safe for concurrent use.
+type Clock struct {
+	now    time.Time
+	timers map[*timer]struct{} // Active timers
+	m      sync.Mutex
+}
+
+// timer is a fake mcache.Timer
+type timer struct {
+	clock *Clock
+	when  time.Time
+	f     func()
+}
+
+var _ mcache.Clock = (*Clock)(nil)
+
+// New creates a clock set to the given time.
+func New(now time.Time) *Clock {
+	return &Clock{
+		now:    now,
+		timers: make(map[*timer]struct{}),
+	}
+}
+
+// Now returns the current time of the clock.
+func (c *Clock) Now() time.Time {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return c.now
+}
+
+// AfterFunc creates a timer calling f once the clock is advanced by d. Timers with non-positive duration
+// fire on the next Advance, even by zero.
+func (c *Clock) AfterFunc(d time.Duration, f func()) mcache.Timer {
+	t := &timer{
+		clock: c,
+		f:     f,
+	}
+
+	t.Reset(d)
+
+	return t
+}
+
+// Advance moves the clock forward by d, calling functions of timers due in the meantime in order of their time.
+// The clock shows the time of each timer while its function is called.
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+// Set moves the clock to the given time, working as Advance. The clock is never moved backwards.
+func (c *Clock) Set(now time.Time) {
+	for {
+		c.m.Lock()
+
+		var next *timer
+
+		for t := range c.timers {
+			if !t.when.After(now) && (next == nil || t.when.Before(next.when)) {
+				next = t
+			}
+		}
+
+		if next == nil {
+			if now.After(c.now) {
+				c.now = now
+			}
+
+			c.m.Unlock()
+
+			return
+		}
+
+		delete(c.timers, next)
+
+		if next.when.After(c.now) {
+			c.now = next.when
+		}
+
+		c.m.Unlock()
+
+		next.f()
+	}
+}
+
+// Timers returns the number of active timers.
+func (c *Clock) Timers() int {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	return len(c.timers)
+}
+
+// Reset makes the timer fire after d, reporting whether the timer was active.
+func (t *timer) Reset(d time.Duration) bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+
+	_, active := t.clock.timers[t]
+
+	t.when = t.clock.now.Add(d)
+	t.clock.timers[t] = struct{}{}
+
+	return active
+}
+
+// Stop deactivates the timer, reporting whether it was active.
+func (t *timer) Stop() bool {
+	t.clock.m.Lock()
+	defer t.clock.m.Unlock()
+
+	_, active := t.clock.timers[t]
+
+	delete(t.clock.timers, t)
+
+	return active
+}
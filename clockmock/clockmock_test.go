@@ -0,0 +1,109 @@
+package clockmock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/clockmock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := clockmock.New(start)
+
+	var (
+		expired []string
+		times   []time.Time
+	)
+
+	c := mcache.New[string, int](
+		mcache.WithClock(clock),
+		mcache.WithEvictionCallback(func(key string, _ int, reason mcache.Reason) {
+			require.Equal(t, mcache.Expired, reason)
+
+			expired = append(expired, key)
+			times = append(times, clock.Now())
+		}),
+	)
+
+	c.Set("c", 3, 3*time.Minute)
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, 2*time.Minute)
+
+	ttl, ok := c.TTL("b")
+	require.True(t, ok)
+	require.Equal(t, 2*time.Minute, ttl)
+	require.Equal(t, 1, clock.Timers())
+
+	clock.Advance(59 * time.Second)
+	require.Empty(t, expired)
+	require.Equal(t, 3, c.Len())
+
+	clock.Advance(time.Second)
+	require.Equal(t, []string{"a"}, expired)
+
+	require.True(t, c.Refresh("c", time.Hour))
+
+	// Both timers due within the step fire in order
+	clock.Advance(time.Hour)
+	require.Equal(t, []string{"a", "b", "c"}, expired)
+	require.Equal(t, []time.Time{start.Add(time.Minute), start.Add(2 * time.Minute), start.Add(time.Hour + time.Minute)}, times)
+	require.Equal(t, start.Add(time.Hour+time.Minute), clock.Now())
+	require.Zero(t, c.Len())
+	require.Zero(t, clock.Timers())
+}
+
+func TestClockGracePeriod(t *testing.T) {
+	clock := clockmock.New(time.Now())
+	c := mcache.New[int, int](mcache.WithClock(clock), mcache.WithGracePeriod(time.Minute))
+
+	c.Set(1, 1, time.Second)
+
+	clock.Advance(time.Second)
+
+	_, ok := c.Get(1)
+	require.False(t, ok)
+
+	_, ok, expired := c.GetStale(1)
+	require.True(t, ok)
+	require.True(t, expired)
+
+	clock.Advance(time.Minute)
+
+	_, ok, _ = c.GetStale(1)
+	require.False(t, ok)
+}
+
+func TestClockSet(t *testing.T) {
+	start := time.Now()
+	clock := clockmock.New(start)
+
+	fired := 0
+	timer := clock.AfterFunc(0, func() { fired++ })
+
+	clock.Advance(0)
+	require.Equal(t, 1, fired)
+	require.False(t, timer.Stop())
+
+	require.False(t, timer.Reset(time.Second))
+	require.True(t, timer.Reset(2*time.Second))
+
+	// Never moves backwards
+	clock.Set(start.Add(-time.Hour))
+	require.Equal(t, start, clock.Now())
+
+	clock.Set(start.Add(time.Second))
+	require.Equal(t, 1, fired)
+
+	require.True(t, timer.Stop())
+
+	clock.Advance(time.Hour)
+	require.Equal(t, 1, fired)
+}
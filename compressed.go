@@ -0,0 +1,109 @@
+package mcache
+
+import (
+	"bytes"
+	"compress/flate"
+	"io"
+	"sync"
+	"time"
+)
+
+// CompressedValue is a value stored by Compressed, deflate-compressed if it was larger than the threshold.
+type CompressedValue struct {
+	Data       []byte
+	Compressed bool
+}
+
+// Compressed wraps a cache, compressing string and byte slice values larger than the threshold on Set
+// and decompressing them on Get. Values that do not get smaller are stored as is.
+type Compressed[K comparable, V ~string | ~[]byte] struct {
+	cache     *Cache[K, CompressedValue]
+	threshold int
+	writers   sync.Pool
+}
+
+// NewCompressed creates a cache wrapper compressing values of at least threshold bytes.
+func NewCompressed[K comparable, V ~string | ~[]byte](c *Cache[K, CompressedValue], threshold int) *Compressed[K, V] {
+	return &Compressed[K, V]{
+		cache:     c,
+		threshold: threshold,
+		writers: sync.Pool{
+			New: func() any {
+				w, _ := flate.NewWriter(nil, flate.DefaultCompression)
+
+				return w
+			},
+		},
+	}
+}
+
+// Get returns the value from the cache, decompressing it if needed.
+func (c *Compressed[K, V]) Get(key K) (V, bool) {
+	stored, ok := c.cache.Get(key)
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	if !stored.Compressed {
+		return V(stored.Data), true
+	}
+
+	data, err := io.ReadAll(flate.NewReader(bytes.NewReader(stored.Data)))
+	if err != nil {
+		var zero V
+
+		return zero, false
+	}
+
+	return V(data), true
+}
+
+// Set adds or replaces the value with key and given TTL, compressing it if it is large enough.
+func (c *Compressed[K, V]) Set(key K, value V, ttl time.Duration) {
+	c.cache.Set(key, c.compress([]byte(value)), ttl)
+}
+
+// Delete deletes the key from the cache.
+func (c *Compressed[K, V]) Delete(key K) bool {
+	return c.cache.Delete(key)
+}
+
+// Refresh sets new TTL for the key.
+func (c *Compressed[K, V]) Refresh(key K, ttl time.Duration) bool {
+	return c.cache.Refresh(key, ttl)
+}
+
+// Len returns number of items in the cache.
+func (c *Compressed[K, V]) Len() int {
+	return c.cache.Len()
+}
+
+// compress returns the value to store, compressed if that makes it smaller.
+func (c *Compressed[K, V]) compress(data []byte) CompressedValue {
+	if len(data) < c.threshold {
+		return CompressedValue{Data: data}
+	}
+
+	var buf bytes.Buffer
+
+	w := c.writers.Get().(*flate.Writer)
+	w.Reset(&buf)
+
+	_, err := w.Write(data)
+	if err == nil {
+		err = w.Close()
+	}
+
+	c.writers.Put(w)
+
+	if err != nil || buf.Len() >= len(data) {
+		return CompressedValue{Data: data}
+	}
+
+	return CompressedValue{
+		Data:       bytes.Clone(buf.Bytes()),
+		Compressed: true,
+	}
+}
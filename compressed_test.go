@@ -0,0 +1,58 @@
+package mcache_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompressed(t *testing.T) {
+	c := mcache.New[string, mcache.CompressedValue]()
+	z := mcache.NewCompressed[string, string](c, 100)
+
+	payload := strings.Repeat(`{"id":1,"name":"value"},`, 100)
+
+	z.Set("small", "short", 30*time.Millisecond)
+	z.Set("large", payload, 30*time.Millisecond)
+
+	value, ok := z.Get("small")
+	require.True(t, ok)
+	require.Equal(t, "short", value)
+
+	value, ok = z.Get("large")
+	require.True(t, ok)
+	require.Equal(t, payload, value)
+
+	stored, ok := c.Get("small")
+	require.True(t, ok)
+	require.False(t, stored.Compressed)
+
+	stored, ok = c.Get("large")
+	require.True(t, ok)
+	require.True(t, stored.Compressed)
+	require.Less(t, len(stored.Data), len(payload)/10)
+
+	_, ok = z.Get("missing")
+	require.False(t, ok)
+
+	require.Equal(t, 2, z.Len())
+	require.True(t, z.Refresh("small", 10*time.Millisecond))
+	require.True(t, z.Delete("large"))
+
+	b := mcache.NewCompressed[int, []byte](mcache.New[int, mcache.CompressedValue](), 10)
+
+	// Incompressible values are stored as is
+	b.Set(1, []byte("abcdefghijklmnop"), 10*time.Millisecond)
+
+	data, ok := b.Get(1)
+	require.True(t, ok)
+	require.Equal(t, []byte("abcdefghijklmnop"), data)
+
+	assert.Eventually(t, func() bool {
+		return 0 == z.Len()+b.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,122 @@
+package mcache
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Dump writes the internal state of the cache in a readable format for debugging: the eviction queue in order,
+// with expiration time and flags of every item, followed by the results of the queue and map consistency checks.
+// The output format is not stable.
+func (c *Cache[K, V]) Dump(w io.Writer) error {
+	var buf bytes.Buffer
+
+	now := c.now()
+
+	c.m.RLock()
+
+	fmt.Fprintf(&buf, "items: %d, estimated bytes: %d, timer: %t\n", len(c.cache), c.size, c.timer != nil)
+
+	for n, pos := c.head, 0; n != nil && pos <= len(c.cache); n, pos = n.Next, pos+1 {
+		fmt.Fprintf(&buf, "%6d %v expires %s (%s)", pos, n.Key, n.Expires.Format(time.RFC3339Nano), n.Expires.Sub(now))
+
+		if n.Relaxed {
+			buf.WriteString(" relaxed")
+		}
+
+		if n.Priority != PriorityNormal {
+			fmt.Fprintf(&buf, " priority %d", n.Priority)
+		}
+
+		if c.pinned(n.Key) {
+			buf.WriteString(" pinned")
+		}
+
+		if !now.Before(n.Expires) {
+			buf.WriteString(" expired")
+		}
+
+		buf.WriteByte('\n')
+	}
+
+	problems := c.check()
+
+	c.m.RUnlock()
+
+	if len(problems) == 0 {
+		buf.WriteString("consistency: ok\n")
+	}
+
+	for _, p := range problems {
+		fmt.Fprintf(&buf, "consistency: %s\n", p)
+	}
+
+	_, err := buf.WriteTo(w)
+
+	return err
+}
+
+// Verify checks the internal consistency of the cache: that the eviction queue is properly linked and ordered
+// by expiration time, that its head and tail are set right, and that it holds the same items as the map.
+// It returns an error describing all found problems, or nil. Verify walks all items under the read lock,
+// so it is meant for tests rather than production code paths.
+func (c *Cache[K, V]) Verify() error {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return errors.Join(c.check()...)
+}
+
+// check verifies that the queue is properly linked and ordered, and holds the same items as the map,
+// returning the found problems. Must be called under the lock.
+func (c *Cache[K, V]) check() (problems []error) {
+	if (c.head == nil) != (c.tail == nil) {
+		problems = append(problems, fmt.Errorf("head is %v, but tail is %v", c.head != nil, c.tail != nil))
+	}
+
+	if c.head != nil && c.head.Prev != nil {
+		problems = append(problems, fmt.Errorf("head %v has previous item", c.head.Key))
+	}
+
+	if c.tail != nil && c.tail.Next != nil {
+		problems = append(problems, fmt.Errorf("tail %v has next item", c.tail.Key))
+	}
+
+	var (
+		count int
+		last  *item[K]
+	)
+
+	for n := c.head; n != nil; n = n.Next {
+		if count++; count > len(c.cache) {
+			return append(problems, fmt.Errorf("queue is longer than the map of %d items", len(c.cache)))
+		}
+
+		if n.Prev != last {
+			problems = append(problems, fmt.Errorf("item %v is not linked back to the previous item", n.Key))
+		}
+
+		if last != nil && n.Expires.Before(last.Expires) {
+			problems = append(problems, fmt.Errorf("item %v expires before the previous item %v", n.Key, last.Key))
+		}
+
+		if v, ok := c.cache[n.Key]; !ok || v.Ptr != n {
+			problems = append(problems, fmt.Errorf("item %v is not in the map", n.Key))
+		}
+
+		last = n
+	}
+
+	if last != c.tail {
+		problems = append(problems, errors.New("queue does not end at the tail"))
+	}
+
+	if count != len(c.cache) {
+		problems = append(problems, fmt.Errorf("queue holds %d items, the map holds %d", count, len(c.cache)))
+	}
+
+	return
+}
@@ -0,0 +1,63 @@
+package mcache_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDump(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("b", 2, 20*time.Millisecond)
+	c.SetWithPriority("a", 1, 10*time.Millisecond, mcache.PriorityHigh)
+	c.SetWithPrecision("c", 3, 30*time.Millisecond, mcache.Relaxed)
+	c.Pin("b", true)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.Dump(&buf))
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	require.Len(t, lines, 5)
+	require.True(t, strings.HasPrefix(lines[0], "items: 3,"))
+	require.Contains(t, lines[1], " a expires ")
+	require.True(t, strings.HasSuffix(lines[1], " priority 1"))
+	require.Contains(t, lines[2], " b expires ")
+	require.True(t, strings.HasSuffix(lines[2], " pinned"))
+	require.Contains(t, lines[3], " c expires ")
+	require.True(t, strings.HasSuffix(lines[3], " relaxed"))
+	require.Equal(t, "consistency: ok", lines[4])
+
+	c.Clear()
+}
+
+func TestVerify(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	require.NoError(t, c.Verify())
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i, time.Duration(100-i)*time.Millisecond)
+	}
+
+	c.SetMany(map[int]int{200: 1, 201: 2, 202: 3}, 50*time.Millisecond)
+	c.Refresh(10, time.Millisecond)
+	c.Delete(20)
+	c.Evict(5)
+
+	// Rekeying onto an existing key replaces it
+	require.True(t, c.Rekey(30, 40))
+	require.True(t, c.Rekey(41, 41))
+	require.Equal(t, 96, c.Len())
+
+	require.NoError(t, c.Verify())
+
+	c.Clear()
+
+	require.NoError(t, c.Verify())
+}
@@ -0,0 +1,57 @@
+package mcache
+
+// EventType tells what happened to the key.
+type EventType int
+
+const (
+	// EventSet is published when a new key is stored.
+	EventSet EventType = iota
+	// EventUpdate is published when the value of an existing key is replaced or changed.
+	EventUpdate
+	// EventDelete is published when the key is deleted explicitly, popped, or renamed with Rekey.
+	EventDelete
+	// EventExpire is published when the key expires.
+	EventExpire
+	// EventEvict is published when the key is removed with Evict.
+	EventEvict
+	// EventClear is published once when the cache is cleared, with zero key and value.
+	EventClear
+)
+
+// Event is a change of the cache contents published to the Events channel.
+type Event[K comparable, V any] struct {
+	Type  EventType
+	Key   K
+	Value V // The new value for set and update events, the removed value otherwise
+}
+
+// Events returns the channel receiving changes of the cache contents, enabled with WithEvents.
+// Events are published without blocking: when the channel buffer is full, new events are dropped
+// and counted in Stats. Returns nil if events are not enabled.
+func (c *Cache[K, V]) Events() <-chan Event[K, V] {
+	return c.events
+}
+
+// publish sends the event if events are enabled, dropping it if the buffer is full.
+func (c *Cache[K, V]) publish(t EventType, key K, value V) {
+	if c.events == nil {
+		return
+	}
+
+	select {
+	case c.events <- Event[K, V]{Type: t, Key: key, Value: value}:
+	default:
+		c.stats.droppedEvents.Add(1)
+	}
+}
+
+// publishRemoval publishes removal of the key, must be called before the key is deleted.
+func (c *Cache[K, V]) publishRemoval(t EventType, key K) {
+	if c.events == nil {
+		return
+	}
+
+	if v, ok := c.cache[key]; ok {
+		c.publish(t, key, v.Value)
+	}
+}
@@ -0,0 +1,85 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvents(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithEvents(5))
+
+	c.Set("a", 1, 20*time.Millisecond)
+	c.Set("a", 2, 20*time.Millisecond)
+	c.Update("a", 3)
+	c.Set("b", 4, 10*time.Millisecond)
+	c.Delete("a")
+
+	events := c.Events()
+
+	require.Equal(t, mcache.Event[string, int]{Type: mcache.EventSet, Key: "a", Value: 1}, <-events)
+	require.Equal(t, mcache.Event[string, int]{Type: mcache.EventUpdate, Key: "a", Value: 2}, <-events)
+	require.Equal(t, mcache.Event[string, int]{Type: mcache.EventUpdate, Key: "a", Value: 3}, <-events)
+	require.Equal(t, mcache.Event[string, int]{Type: mcache.EventSet, Key: "b", Value: 4}, <-events)
+	require.Equal(t, mcache.Event[string, int]{Type: mcache.EventDelete, Key: "a", Value: 3}, <-events)
+
+	select {
+	case e := <-events:
+		require.Equal(t, mcache.Event[string, int]{Type: mcache.EventExpire, Key: "b", Value: 4}, e)
+	case <-time.After(100 * time.Millisecond):
+		require.Fail(t, "no expiration event")
+	}
+
+	for i := 0; i < 7; i++ {
+		c.Set("c", i, 10*time.Millisecond)
+	}
+
+	require.Len(t, events, 5)
+	require.EqualValues(t, 2, c.Stats().Dropped)
+
+	require.Nil(t, mcache.New[string, int]().Events())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestEventsPopMergeRekey(t *testing.T) {
+	type event = mcache.Event[string, int]
+
+	c := mcache.New[string, int](mcache.WithEvents(10))
+	events := c.Events()
+
+	c.Set("a", 1, time.Minute)
+	c.Set("b", 2, time.Hour)
+	require.Equal(t, event{Type: mcache.EventSet, Key: "a", Value: 1}, <-events)
+	require.Equal(t, event{Type: mcache.EventSet, Key: "b", Value: 2}, <-events)
+
+	_, _, ok := c.PopOldest()
+	require.True(t, ok)
+	require.Equal(t, event{Type: mcache.EventDelete, Key: "a", Value: 1}, <-events)
+	require.EqualValues(t, 1, c.Stats().Deletes)
+
+	other := mcache.New[string, int]()
+	other.Set("b", 3, time.Hour)
+	other.Set("c", 4, time.Hour)
+
+	c.Merge(other, nil)
+	require.ElementsMatch(t, []event{
+		{Type: mcache.EventUpdate, Key: "b", Value: 3},
+		{Type: mcache.EventSet, Key: "c", Value: 4},
+	}, []event{<-events, <-events})
+
+	require.True(t, c.Rekey("b", "d"))
+	require.Equal(t, event{Type: mcache.EventDelete, Key: "b", Value: 3}, <-events)
+	require.Equal(t, event{Type: mcache.EventSet, Key: "d", Value: 3}, <-events)
+
+	require.True(t, c.Rekey("d", "c"))
+	require.Equal(t, event{Type: mcache.EventDelete, Key: "d", Value: 3}, <-events)
+	require.Equal(t, event{Type: mcache.EventUpdate, Key: "c", Value: 3}, <-events)
+
+	require.Empty(t, events)
+}
@@ -8,7 +8,6 @@ package main
 
 import (
 	"fmt"
-	"sync"
 	"time"
 
 	"github.com/dmytro-vovk/go-mcache"
@@ -36,7 +35,6 @@ func main() {
 type Counter struct {
 	c   *mcache.Cache[string, int]
 	ttl time.Duration
-	m   sync.Mutex
 }
 
 func NewCounter(ttl time.Duration) *Counter {
@@ -47,20 +45,11 @@ func NewCounter(ttl time.Duration) *Counter {
 }
 
 func (c *Counter) Inc(key string) {
-	c.m.Lock()
-
-	value, _ := c.c.Get(key)
-	c.c.Set(key, value+1, c.ttl)
-
-	c.m.Unlock()
+	c.c.Compute(key, func(value int, _ bool) (int, time.Duration, bool) {
+		return value + 1, c.ttl, false
+	})
 }
 
 func (c *Counter) Get(key string) int {
-	c.m.Lock()
-
-	value, _ := c.c.Get(key)
-
-	c.m.Unlock()
-
-	return value
+	return c.c.GetOrDefault(key, 0)
 }
@@ -0,0 +1,111 @@
+package mcache
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// fixtureEntry is a single line of a fixture
+type fixtureEntry[K comparable, V any] struct {
+	Key   K      `json:"key"`
+	Value V      `json:"value"`
+	TTL   string `json:"ttl"`
+}
+
+// ExportFixture writes the cache contents in a stable human-readable format, suitable for storing as a test fixture.
+// Each item is written as a JSON object on its own line, sorted by key, with remaining TTL rounded to seconds.
+// Keys and values must be JSON-serializable.
+func (c *Cache[K, V]) ExportFixture(w io.Writer) error {
+	now := c.now()
+
+	var entries []fixtureEntry[K, V]
+
+	c.m.RLock()
+
+	for n := c.head; n != nil; n = n.Next {
+		if !c.alive(n) {
+			continue
+		}
+
+		entries = append(entries, fixtureEntry[K, V]{
+			Key:   n.Key,
+			Value: c.cache[n.Key].Value,
+			TTL:   n.Expires.Sub(now).Round(time.Second).String(),
+		})
+	}
+
+	c.m.RUnlock()
+
+	lines := make([][]byte, 0, len(entries))
+	keys := make([][]byte, 0, len(entries))
+
+	for i := range entries {
+		key, err := json.Marshal(entries[i].Key)
+		if err != nil {
+			return err
+		}
+
+		line, err := json.Marshal(entries[i])
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, key)
+		lines = append(lines, line)
+	}
+
+	sort.Sort(fixtureLines{keys: keys, lines: lines})
+
+	for _, line := range lines {
+		if _, err := w.Write(append(line, '\n')); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadFixture stores items read from the fixture written by ExportFixture, with TTLs relative to the current time.
+func (c *Cache[K, V]) LoadFixture(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<24)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		var entry fixtureEntry[K, V]
+
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("fixture line %d: %w", line, err)
+		}
+
+		ttl, err := time.ParseDuration(entry.TTL)
+		if err != nil {
+			return fmt.Errorf("fixture line %d: %w", line, err)
+		}
+
+		c.Set(entry.Key, entry.Value, ttl)
+	}
+
+	return scanner.Err()
+}
+
+// fixtureLines sorts fixture lines by encoded keys
+type fixtureLines struct {
+	keys  [][]byte
+	lines [][]byte
+}
+
+func (f fixtureLines) Len() int           { return len(f.keys) }
+func (f fixtureLines) Less(i, j int) bool { return bytes.Compare(f.keys[i], f.keys[j]) < 0 }
+func (f fixtureLines) Swap(i, j int) {
+	f.keys[i], f.keys[j] = f.keys[j], f.keys[i]
+	f.lines[i], f.lines[j] = f.lines[j], f.lines[i]
+}
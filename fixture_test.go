@@ -0,0 +1,41 @@
+package mcache_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFixture(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("b", 2, time.Second)
+	c.Set("a", 1, time.Second)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.ExportFixture(&buf))
+	require.Equal(t, `{"key":"a","value":1,"ttl":"1s"}
+{"key":"b","value":2,"ttl":"1s"}
+`, buf.String())
+
+	l := mcache.New[string, int]()
+
+	require.NoError(t, l.LoadFixture(strings.NewReader(`{"key":"x","value":10,"ttl":"50ms"}`+"\n\n")))
+
+	if v, ok := l.Get("x"); assert.True(t, ok) {
+		assert.Equal(t, 10, v)
+	}
+
+	require.Error(t, l.LoadFixture(strings.NewReader(`{"key":"x","value":"y","ttl":"1s"}`)))
+	require.Error(t, l.LoadFixture(strings.NewReader(`{"key":"x","value":1,"ttl":"soon"}`)))
+
+	assert.Eventually(t, func() bool {
+		return 0 == l.Len()+c.Len()
+	}, 1100*time.Millisecond, 20*time.Millisecond)
+}
@@ -0,0 +1,10 @@
+//go:build go1.24
+
+package mcache
+
+import "hash/maphash"
+
+// hashComparable hashes keys of any comparable type, equal keys have equal hashes.
+func hashComparable[K comparable](seed maphash.Seed, key K) uint64 {
+	return maphash.Comparable(seed, key)
+}
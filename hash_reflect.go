@@ -0,0 +1,76 @@
+//go:build !go1.24
+
+package mcache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"math"
+	"reflect"
+)
+
+// hashComparable hashes keys of any comparable type, equal keys have equal hashes.
+// It walks the key with reflection, as maphash.Comparable is not available before Go 1.24.
+func hashComparable[K comparable](seed maphash.Seed, key K) uint64 {
+	var h maphash.Hash
+
+	h.SetSeed(seed)
+
+	hashValue(&h, reflect.ValueOf(&key).Elem())
+
+	return h.Sum64()
+}
+
+// hashValue writes the value to the hash. Floats are normalized so that positive and negative zeros,
+// which are equal, hash the same. Interfaces are hashed by their dynamic type and value.
+func hashValue(h *maphash.Hash, v reflect.Value) {
+	var buf [8]byte
+
+	switch v.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			h.WriteByte(1)
+		} else {
+			h.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		h.Write(binary.LittleEndian.AppendUint64(buf[:0], uint64(v.Int())))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		h.Write(binary.LittleEndian.AppendUint64(buf[:0], v.Uint()))
+	case reflect.Float32, reflect.Float64:
+		hashFloat(h, v.Float())
+	case reflect.Complex64, reflect.Complex128:
+		hashFloat(h, real(v.Complex()))
+		hashFloat(h, imag(v.Complex()))
+	case reflect.String:
+		h.WriteString(v.String())
+	case reflect.Pointer, reflect.Chan, reflect.UnsafePointer:
+		h.Write(binary.LittleEndian.AppendUint64(buf[:0], uint64(v.Pointer())))
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			hashValue(h, v.Index(i))
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			hashValue(h, v.Field(i))
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			h.WriteByte(0)
+
+			return
+		}
+
+		h.WriteString(v.Elem().Type().String())
+		hashValue(h, v.Elem())
+	}
+}
+
+// hashFloat writes the float to the hash, with both zeros written the same.
+func hashFloat(h *maphash.Hash, f float64) {
+	if f == 0 {
+		f = 0
+	}
+
+	h.Write(binary.LittleEndian.AppendUint64(nil, math.Float64bits(f)))
+}
@@ -0,0 +1,229 @@
+package mcache
+
+import (
+	"hash/maphash"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Count-min sketch dimensions, columns are split between the shards
+const (
+	sketchDepth = 4
+	sketchWidth = 2048
+	hotShards   = 16
+	shardWidth  = sketchWidth / hotShards
+)
+
+// hotKeys estimates access frequency of keys with a count-min sketch over a sliding window,
+// keeping a bounded set of the most frequent keys. Keys are split between shards by their hash,
+// so reads of different keys rarely contend.
+type hotKeys[K comparable] struct {
+	seed     maphash.Seed
+	clock    Clock
+	half     time.Duration
+	capacity int
+	shards   [hotShards]hotShard[K]
+}
+
+// hotShard counts accesses of the keys hashed to it
+type hotShard[K comparable] struct {
+	m        sync.Mutex
+	sketches [2][sketchDepth][shardWidth]uint32 // Counts of the current and the previous half of the window
+	current  int
+	rotated  time.Time
+	top      map[K]uint64 // Candidate keys with their estimated counts
+	min      uint64       // Lower bound of candidate counts once there are capacity candidates
+}
+
+func newHotKeys[K comparable](window time.Duration, capacity int, clock Clock) *hotKeys[K] {
+	h := &hotKeys[K]{
+		seed:     maphash.MakeSeed(),
+		clock:    clock,
+		half:     window / 2,
+		capacity: capacity,
+	}
+
+	now := clock.Now()
+
+	for i := range h.shards {
+		h.shards[i].rotated = now
+		h.shards[i].top = make(map[K]uint64)
+	}
+
+	return h
+}
+
+// TopKeys returns up to n most frequently read keys over the window set with WithHotKeyTracking, most frequent first.
+// The frequencies are estimated, so keys with close frequencies may be ordered arbitrarily.
+// Returns nil if hot key tracking is not enabled.
+func (c *Cache[K, V]) TopKeys(n int) []K {
+	if c.hot == nil {
+		return nil
+	}
+
+	return c.hot.topKeys(n)
+}
+
+// add records the key access.
+func (h *hotKeys[K]) add(key K) {
+	sum := hashKey(h.seed, key)
+	shard := &h.shards[sum%hotShards]
+	sum /= hotShards
+
+	shard.m.Lock()
+
+	h.rotate(shard)
+
+	current, previous := &shard.sketches[shard.current], &shard.sketches[1-shard.current]
+
+	var estimate uint64
+
+	for row := 0; row < sketchDepth; row++ {
+		i := sketchIndex(sum, row)
+
+		current[row][i]++
+
+		if count := uint64(current[row][i]) + uint64(previous[row][i]); row == 0 || count < estimate {
+			estimate = count
+		}
+	}
+
+	h.offer(shard, key, estimate)
+
+	shard.m.Unlock()
+}
+
+// offer puts the key among candidates of the shard if its estimated count is high enough.
+// Candidates are only scanned when the key may replace one of them.
+func (h *hotKeys[K]) offer(shard *hotShard[K], key K, estimate uint64) {
+	if _, ok := shard.top[key]; ok || len(shard.top) < h.capacity {
+		shard.top[key] = estimate
+
+		return
+	}
+
+	if estimate <= shard.min {
+		return
+	}
+
+	var (
+		minKey           K
+		minCount, runner uint64
+		first, hasRunner = true, false
+	)
+
+	for k, count := range shard.top {
+		switch {
+		case first || count < minCount:
+			if !first {
+				runner, hasRunner = minCount, true
+			}
+
+			minKey, minCount, first = k, count, false
+		case !hasRunner || count < runner:
+			runner, hasRunner = count, true
+		}
+	}
+
+	if estimate <= minCount {
+		shard.min = minCount
+
+		return
+	}
+
+	delete(shard.top, minKey)
+	shard.top[key] = estimate
+
+	if shard.min = estimate; hasRunner && runner < estimate {
+		shard.min = runner
+	}
+}
+
+// rotate starts the new half of the window once the current one is over, forgetting the oldest counts.
+func (h *hotKeys[K]) rotate(shard *hotShard[K]) {
+	now := h.clock.Now()
+
+	elapsed := now.Sub(shard.rotated)
+	if elapsed < h.half {
+		return
+	}
+
+	shard.current = 1 - shard.current
+	shard.sketches[shard.current] = [sketchDepth][shardWidth]uint32{}
+	shard.rotated = now
+
+	if elapsed >= 2*h.half {
+		// Both halves are over
+		shard.sketches[1-shard.current] = [sketchDepth][shardWidth]uint32{}
+	}
+
+	shard.min = 0
+
+	// Candidates counted in the forgotten half only are no longer hot
+	for k := range shard.top {
+		sum := hashKey(h.seed, k) / hotShards
+
+		var estimate uint64
+
+		for row := 0; row < sketchDepth; row++ {
+			i := sketchIndex(sum, row)
+
+			if count := uint64(shard.sketches[0][row][i]) + uint64(shard.sketches[1][row][i]); row == 0 || count < estimate {
+				estimate = count
+			}
+		}
+
+		if estimate == 0 {
+			delete(shard.top, k)
+		} else {
+			shard.top[k] = estimate
+		}
+	}
+}
+
+// sketchIndex returns the sketch column of the hash for the row.
+func sketchIndex(sum uint64, row int) uint64 {
+	return (sum + uint64(row)*(sum>>32|1)) % shardWidth
+}
+
+func (h *hotKeys[K]) topKeys(n int) []K {
+	counts := make(map[K]uint64)
+
+	for i := range h.shards {
+		shard := &h.shards[i]
+
+		shard.m.Lock()
+
+		h.rotate(shard)
+
+		for k, count := range shard.top {
+			counts[k] = count
+		}
+
+		shard.m.Unlock()
+	}
+
+	keys := make([]K, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return counts[keys[i]] > counts[keys[j]]
+	})
+
+	if n > h.capacity {
+		n = h.capacity
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	if len(keys) > n {
+		keys = keys[:n]
+	}
+
+	return keys
+}
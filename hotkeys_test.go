@@ -0,0 +1,79 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/clockmock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopKeys(t *testing.T) {
+	c := mcache.New[string, int](mcache.WithHotKeyTracking(100*time.Millisecond, 3))
+
+	for i := 0; i < 100; i++ {
+		c.Get("hot")
+
+		if i%2 == 0 {
+			c.Get("warm")
+		}
+
+		if i%10 == 0 {
+			c.Get("cool")
+		}
+
+		c.Get(string(rune('a' + i%20)))
+	}
+
+	require.Equal(t, []string{"hot", "warm"}, c.TopKeys(2))
+	require.Len(t, c.TopKeys(10), 3)
+	require.Empty(t, c.TopKeys(-1))
+
+	time.Sleep(110 * time.Millisecond)
+
+	c.Get("new")
+
+	require.Equal(t, []string{"new"}, c.TopKeys(3))
+
+	require.Nil(t, mcache.New[string, int]().TopKeys(1))
+}
+
+func TestTopKeysClock(t *testing.T) {
+	clock := clockmock.New(time.Now())
+	c := mcache.New[int, int](mcache.WithClock(clock), mcache.WithHotKeyTracking(time.Minute, 5))
+
+	for i := 0; i < 1000; i++ {
+		c.Get(i % 10)
+		c.Get(i % 3)
+	}
+
+	require.ElementsMatch(t, []int{0, 1, 2}, c.TopKeys(3))
+	require.Len(t, c.TopKeys(100), 5)
+
+	clock.Advance(30 * time.Second)
+
+	c.Get(42)
+
+	require.Len(t, c.TopKeys(100), 5)
+
+	clock.Advance(time.Minute)
+
+	c.Get(42)
+
+	require.Equal(t, []int{42}, c.TopKeys(5))
+}
+
+func BenchmarkHotKeyTracking(b *testing.B) {
+	c := mcache.New[int, int](mcache.WithHotKeyTracking(time.Minute, 100))
+
+	for i := 0; i < 1000; i++ {
+		c.Set(i, i, time.Minute)
+	}
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Get(i % 1000)
+		}
+	})
+}
@@ -0,0 +1,353 @@
+/*
+Package httpadmin provides an HTTP handler to inspect and manage caches in a running service.
+
+Caches are registered under names, and the handler serves JSON endpoints for each of them:
+
+	GET    /                       names of registered caches
+	GET    /{name}/stats           operation counters, number of items, and whether the cache is disabled
+	GET    /{name}/keys/{key}      value of the key with its expiration time
+	DELETE /{name}/keys/{key}      deletes the key
+	POST   /{name}/sweep           removes expired items
+	GET    /{name}/snapshot        binary snapshot of the cache, as written by WriteSnapshot,
+	                               streamed as it is encoded; the connection is aborted on a failure mid-stream
+	POST   /{name}/disable         makes the cache always miss
+	POST   /{name}/enable          enables the disabled cache
+	POST   /{name}/ttl?prefix=&ttl=&future=
+	                               overrides TTL of keys starting with the prefix
+
+The handler does not authenticate requests, so it must only be exposed on an internal listener.
+*/
+package httpadmin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+)
+
+// Handler serves admin endpoints of registered caches.
+type Handler struct {
+	caches map[string]admin
+	m      sync.RWMutex
+}
+
+// admin is a type-erased registered cache
+type admin interface {
+	stats() Stats
+	lookup(key string) (*Item, error)
+	delete(key string) (bool, error)
+	sweep() int
+	snapshot(w io.Writer) error
+	disable(disabled bool)
+	overrideTTL(prefix string, ttl time.Duration, future bool) int
+}
+
+// Stats is the response of the stats endpoint.
+type Stats struct {
+	mcache.Stats
+	Len      int  `json:"len"`
+	Disabled bool `json:"disabled"`
+}
+
+// Item is the response of the key lookup endpoint.
+type Item struct {
+	Key     string    `json:"key"`
+	Value   any       `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// New creates an admin handler with no caches registered.
+func New() *Handler {
+	return &Handler{
+		caches: make(map[string]admin),
+	}
+}
+
+// Register adds the cache to the handler under the name, replacing the cache registered before under the same name.
+// parseKey converts keys given in URLs to the cache key type.
+func Register[K comparable, V any](h *Handler, name string, c *mcache.Cache[K, V], parseKey func(string) (K, error)) {
+	h.m.Lock()
+
+	h.caches[name] = &registered[K, V]{
+		cache:    c,
+		parseKey: parseKey,
+	}
+
+	h.m.Unlock()
+}
+
+// StringKey is the parseKey function for caches with string keys.
+func StringKey(s string) (string, error) {
+	return s, nil
+}
+
+// IntKey is the parseKey function for caches with int keys.
+func IntKey(s string) (int, error) {
+	return strconv.Atoi(s)
+}
+
+// ServeHTTP routes the request to the endpoint.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/")
+
+	if path == "" {
+		h.list(w, r)
+
+		return
+	}
+
+	name, action, _ := strings.Cut(path, "/")
+
+	h.m.RLock()
+	c, ok := h.caches[name]
+	h.m.RUnlock()
+
+	if !ok {
+		writeError(w, http.StatusNotFound, "cache not found")
+
+		return
+	}
+
+	if key, ok := strings.CutPrefix(action, "keys/"); ok {
+		h.key(w, r, c, key)
+
+		return
+	}
+
+	switch action {
+	case "stats":
+		if allow(w, r, http.MethodGet) {
+			writeJSON(w, c.stats())
+		}
+	case "sweep":
+		if allow(w, r, http.MethodPost) {
+			writeJSON(w, map[string]int{"swept": c.sweep()})
+		}
+	case "snapshot":
+		if allow(w, r, http.MethodGet) {
+			h.snapshot(w, c)
+		}
+	case "disable", "enable":
+		if allow(w, r, http.MethodPost) {
+			c.disable(action == "disable")
+			writeJSON(w, map[string]bool{"disabled": action == "disable"})
+		}
+	case "ttl":
+		if allow(w, r, http.MethodPost) {
+			h.overrideTTL(w, r, c)
+		}
+	default:
+		writeError(w, http.StatusNotFound, "unknown endpoint")
+	}
+}
+
+func (h *Handler) list(w http.ResponseWriter, r *http.Request) {
+	if !allow(w, r, http.MethodGet) {
+		return
+	}
+
+	h.m.RLock()
+
+	names := make([]string, 0, len(h.caches))
+	for name := range h.caches {
+		names = append(names, name)
+	}
+
+	h.m.RUnlock()
+
+	sort.Strings(names)
+
+	writeJSON(w, names)
+}
+
+func (h *Handler) key(w http.ResponseWriter, r *http.Request, c admin, key string) {
+	switch r.Method {
+	case http.MethodGet:
+		item, err := c.lookup(key)
+
+		switch {
+		case err != nil:
+			writeError(w, http.StatusBadRequest, err.Error())
+		case item == nil:
+			writeError(w, http.StatusNotFound, "key not found")
+		default:
+			writeJSON(w, item)
+		}
+	case http.MethodDelete:
+		deleted, err := c.delete(key)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+
+			return
+		}
+
+		writeJSON(w, map[string]bool{"deleted": deleted})
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (h *Handler) overrideTTL(w http.ResponseWriter, r *http.Request, c admin) {
+	q := r.URL.Query()
+
+	ttl, err := time.ParseDuration(q.Get("ttl"))
+	if err != nil || ttl <= 0 {
+		writeError(w, http.StatusBadRequest, "ttl must be a positive duration")
+
+		return
+	}
+
+	future := false
+
+	if f := q.Get("future"); f != "" {
+		if future, err = strconv.ParseBool(f); err != nil {
+			writeError(w, http.StatusBadRequest, "future must be a boolean")
+
+			return
+		}
+	}
+
+	writeJSON(w, map[string]int{"updated": c.overrideTTL(q.Get("prefix"), ttl, future)})
+}
+
+// snapshot streams the snapshot to the response without buffering it.
+// A failure before anything was written is reported with an error status,
+// after that the connection is aborted so the client cannot mistake the truncated body for a complete snapshot.
+func (h *Handler) snapshot(w http.ResponseWriter, c admin) {
+	sw := &startWriter{w: w}
+
+	if err := c.snapshot(sw); err != nil {
+		if !sw.started {
+			writeError(w, http.StatusInternalServerError, err.Error())
+
+			return
+		}
+
+		panic(http.ErrAbortHandler)
+	}
+
+	sw.start()
+}
+
+// startWriter sets the response headers on the first write
+type startWriter struct {
+	w       http.ResponseWriter
+	started bool
+}
+
+func (s *startWriter) start() {
+	if !s.started {
+		s.started = true
+		s.w.Header().Set("Content-Type", "application/octet-stream")
+	}
+}
+
+func (s *startWriter) Write(p []byte) (int, error) {
+	s.start()
+
+	return s.w.Write(p)
+}
+
+// allow reports whether the request has the method, responding with an error if it does not.
+func allow(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+
+	w.Header().Set("Allow", method)
+	writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(append(data, '\n'))
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	data, _ := json.Marshal(map[string]string{"error": msg})
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(append(data, '\n'))
+}
+
+// registered adapts a typed cache to the admin interface
+type registered[K comparable, V any] struct {
+	cache    *mcache.Cache[K, V]
+	parseKey func(string) (K, error)
+}
+
+func (r *registered[K, V]) stats() Stats {
+	return Stats{
+		Stats:    r.cache.Stats(),
+		Len:      r.cache.Len(),
+		Disabled: r.cache.Disabled(),
+	}
+}
+
+func (r *registered[K, V]) lookup(s string) (*Item, error) {
+	key, err := r.parseKey(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key: %w", err)
+	}
+
+	value, expires, ok := r.cache.Peek(key)
+	if !ok {
+		return nil, nil
+	}
+
+	return &Item{
+		Key:     s,
+		Value:   value,
+		Expires: expires,
+	}, nil
+}
+
+func (r *registered[K, V]) delete(s string) (bool, error) {
+	key, err := r.parseKey(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid key: %w", err)
+	}
+
+	return r.cache.Delete(key), nil
+}
+
+func (r *registered[K, V]) sweep() int {
+	return r.cache.Sweep()
+}
+
+func (r *registered[K, V]) snapshot(w io.Writer) error {
+	return r.cache.WriteSnapshot(w)
+}
+
+func (r *registered[K, V]) disable(disabled bool) {
+	if disabled {
+		r.cache.Disable()
+	} else {
+		r.cache.Enable()
+	}
+}
+
+func (r *registered[K, V]) overrideTTL(prefix string, ttl time.Duration, future bool) int {
+	return r.cache.OverrideTTL(func(key K) bool {
+		return strings.HasPrefix(fmt.Sprint(key), prefix)
+	}, ttl, future)
+}
@@ -0,0 +1,159 @@
+package httpadmin_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/httpadmin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func do(h http.Handler, method, target string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+
+	h.ServeHTTP(w, httptest.NewRequest(method, target, nil))
+
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	users := mcache.New[string, string]()
+	sessions := mcache.New[int, int]()
+
+	h := httpadmin.New()
+	httpadmin.Register(h, "users", users, httpadmin.StringKey)
+	httpadmin.Register(h, "sessions", sessions, httpadmin.IntKey)
+
+	users.Set("user:1", "alice", 50*time.Millisecond)
+	users.Set("user:2", "bob", 50*time.Millisecond)
+	sessions.Set(7, 42, 30*time.Millisecond)
+
+	w := do(h, http.MethodGet, "/")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `["sessions","users"]`, w.Body.String())
+
+	_, expires, _ := sessions.GetWithExpiry(7)
+	expiresJSON, _ := expires.MarshalJSON()
+
+	w = do(h, http.MethodGet, "/sessions/keys/7")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.JSONEq(t, `{"key":"7","value":42,"expires":`+string(expiresJSON)+`}`, w.Body.String())
+
+	require.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/sessions/keys/8").Code)
+	require.Equal(t, http.StatusBadRequest, do(h, http.MethodGet, "/sessions/keys/x").Code)
+	require.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/nope/stats").Code)
+	require.Equal(t, http.StatusNotFound, do(h, http.MethodGet, "/users/nope").Code)
+	require.Equal(t, http.StatusMethodNotAllowed, do(h, http.MethodGet, "/users/sweep").Code)
+
+	stats := sessions.Stats()
+	require.Equal(t, uint64(1), stats.Hits)
+	require.Equal(t, uint64(0), stats.Misses)
+
+	w = do(h, http.MethodDelete, "/users/keys/user:2")
+	require.JSONEq(t, `{"deleted":true}`, w.Body.String())
+
+	w = do(h, http.MethodGet, "/users/stats")
+	require.JSONEq(t, `{"Hits":0,"Misses":0,"Sets":2,"Deletes":1,"Expirations":0,"Evictions":0,"Dropped":0,"Rejected":0,"len":1,"disabled":false}`, w.Body.String())
+
+	w = do(h, http.MethodPost, "/users/ttl?prefix=user:&ttl=20ms")
+	require.JSONEq(t, `{"updated":1}`, w.Body.String())
+	require.Equal(t, http.StatusBadRequest, do(h, http.MethodPost, "/users/ttl?ttl=soon").Code)
+
+	w = do(h, http.MethodPost, "/users/disable")
+	require.JSONEq(t, `{"disabled":true}`, w.Body.String())
+	require.True(t, users.Disabled())
+
+	do(h, http.MethodPost, "/users/enable")
+	require.False(t, users.Disabled())
+
+	w = do(h, http.MethodGet, "/sessions/snapshot")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	restored := mcache.New[int, int]()
+	require.NoError(t, restored.ReadSnapshot(bytes.NewReader(w.Body.Bytes())))
+	require.Equal(t, []int{7}, restored.Keys())
+
+	time.Sleep(40 * time.Millisecond)
+
+	w = do(h, http.MethodPost, "/sessions/sweep")
+	require.Equal(t, http.StatusOK, w.Code)
+
+	assert.Eventually(t, func() bool {
+		return 0 == users.Len()+sessions.Len()+restored.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+// unencodable fails to marshal once more than limit values were marshaled
+type unencodable struct {
+	n     *int
+	limit int
+}
+
+func (u *unencodable) MarshalBinary() ([]byte, error) {
+	if *u.n++; *u.n > u.limit {
+		return nil, errors.New("cannot encode")
+	}
+
+	return make([]byte, 1024), nil
+}
+
+func (u *unencodable) UnmarshalBinary([]byte) error {
+	return nil
+}
+
+func TestSnapshotError(t *testing.T) {
+	c := mcache.New[int, unencodable]()
+
+	var n int
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, unencodable{n: &n, limit: 2}, time.Minute)
+	}
+
+	h := httpadmin.New()
+	httpadmin.Register(h, "broken", c, httpadmin.IntKey)
+
+	w := do(h, http.MethodGet, "/broken/snapshot")
+	require.Equal(t, http.StatusInternalServerError, w.Code)
+	require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+	require.JSONEq(t, `{"error":"cannot encode"}`, w.Body.String())
+}
+
+func TestSnapshotErrorMidStream(t *testing.T) {
+	c := mcache.New[int, unencodable]()
+
+	var n int
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, unencodable{n: &n, limit: 50}, time.Minute)
+	}
+
+	h := httpadmin.New()
+	httpadmin.Register(h, "broken", c, httpadmin.IntKey)
+
+	s := httptest.NewServer(h)
+	defer s.Close()
+
+	resp, err := s.Client().Get(s.URL + "/broken/snapshot")
+	require.NoError(t, err)
+
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "application/octet-stream", resp.Header.Get("Content-Type"))
+
+	_, err = io.ReadAll(resp.Body)
+	require.ErrorIs(t, err, io.ErrUnexpectedEOF)
+}
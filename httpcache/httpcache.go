@@ -0,0 +1,189 @@
+/*
+Package httpcache provides net/http middleware and a client transport caching responses in an mcache.
+
+Only GET and HEAD requests are cached, keyed by method, host, and URL. The TTL of a response is taken from
+the Cache-Control header, preferring s-maxage over max-age, and falls back to the default TTL.
+Responses marked as no-store, no-cache, or private, responses setting cookies or varying by request headers,
+and requests sending Cache-Control: no-store or no-cache bypass the cache. Requests with Authorization header
+are only served and cached responses marked as public, s-maxage, or must-revalidate, as RFC 9111 requires
+from shared caches.
+*/
+package httpcache
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+)
+
+// CachedResponse is a stored response.
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+// Middleware returns the middleware serving cached responses from c, and caching responses of the wrapped handler
+// for defaultTTL unless Cache-Control sets a different one. Zero defaultTTL caches only responses with explicit max-age.
+func Middleware(c *mcache.Cache[string, CachedResponse], defaultTTL time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cacheableRequest(r) {
+				next.ServeHTTP(w, r)
+
+				return
+			}
+
+			key := Key(r)
+
+			if resp, ok := c.Get(key); ok && shareable(r, resp.Header) {
+				resp.write(w)
+
+				return
+			}
+
+			rec := &recorder{
+				ResponseWriter: w,
+				status:         http.StatusOK,
+			}
+
+			next.ServeHTTP(rec, r)
+
+			if ttl, ok := TTL(rec.status, w.Header(), defaultTTL); ok && shareable(r, w.Header()) {
+				c.Set(key, CachedResponse{
+					StatusCode: rec.status,
+					Header:     w.Header().Clone(),
+					Body:       rec.body.Bytes(),
+				}, ttl)
+			}
+		})
+	}
+}
+
+// Key returns the cache key of the request, made of its method, host, and request URI.
+func Key(r *http.Request) string {
+	host := r.URL.Host
+	if host == "" {
+		host = r.Host
+	}
+
+	return r.Method + " " + host + r.URL.RequestURI()
+}
+
+// TTL returns for how long the response can be cached, based on its status code and Cache-Control header.
+// Returns false if the response must not be cached.
+func TTL(status int, header http.Header, defaultTTL time.Duration) (time.Duration, bool) {
+	if !cacheableStatus(status) || header.Get("Set-Cookie") != "" || header.Get("Vary") != "" {
+		return 0, false
+	}
+
+	ttl := defaultTTL
+	shared := false
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.ToLower(strings.TrimSpace(directive)), "=")
+
+		switch name {
+		case "no-store", "no-cache", "private":
+			return 0, false
+		case "max-age", "s-maxage":
+			seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+			if err != nil || shared && name == "max-age" {
+				continue
+			}
+
+			ttl, shared = time.Duration(seconds)*time.Second, name == "s-maxage"
+		}
+	}
+
+	return ttl, ttl > 0
+}
+
+// cacheableRequest reports whether the response to the request may be served from the cache.
+func cacheableRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	cc := strings.ToLower(r.Header.Get("Cache-Control"))
+
+	return !strings.Contains(cc, "no-store") && !strings.Contains(cc, "no-cache")
+}
+
+// shareable reports whether the response with the header may be stored or served for the request.
+// Responses to requests with credentials must be explicitly allowed for shared caches (RFC 9111, section 3.5).
+func shareable(r *http.Request, header http.Header) bool {
+	if r.Header.Get("Authorization") == "" {
+		return true
+	}
+
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(directive)), "=")
+
+		switch name {
+		case "public", "s-maxage", "must-revalidate":
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheableStatus reports whether responses with the status code are cacheable by default, as listed in RFC 9110.
+func cacheableStatus(status int) bool {
+	switch status {
+	case http.StatusOK,
+		http.StatusNonAuthoritativeInfo,
+		http.StatusNoContent,
+		http.StatusMultipleChoices,
+		http.StatusMovedPermanently,
+		http.StatusPermanentRedirect,
+		http.StatusNotFound,
+		http.StatusMethodNotAllowed,
+		http.StatusGone,
+		http.StatusRequestURITooLong,
+		http.StatusNotImplemented:
+		return true
+	}
+
+	return false
+}
+
+// write sends the cached response to the client.
+func (r CachedResponse) write(w http.ResponseWriter) {
+	header := w.Header()
+
+	for name, values := range r.Header {
+		header[name] = append([]string(nil), values...)
+	}
+
+	w.WriteHeader(r.StatusCode)
+	w.Write(r.Body)
+}
+
+// recorder passes the response to the client, keeping its status code and body
+type recorder struct {
+	http.ResponseWriter
+	status      int
+	body        bytes.Buffer
+	wroteHeader bool
+}
+
+func (r *recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status, r.wroteHeader = status, true
+	}
+
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *recorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(data)
+
+	return r.ResponseWriter.Write(data)
+}
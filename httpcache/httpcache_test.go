@@ -0,0 +1,154 @@
+package httpcache_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/httpcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestMiddleware(t *testing.T) {
+	c := mcache.New[string, httpcache.CachedResponse]()
+
+	var calls int
+
+	h := httpcache.Middleware(c, 50*time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+
+		switch r.URL.Path {
+		case "/private":
+			w.Header().Set("Cache-Control", "private")
+		case "/long":
+			w.Header().Set("Cache-Control", "public, max-age=60")
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+		case "/error":
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+
+		w.Header().Set("X-Call", fmt.Sprint(calls))
+		fmt.Fprintf(w, "%s %d", r.URL.RequestURI(), calls)
+	}))
+
+	do := func(method, target string, header ...string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, target, nil)
+		if len(header) == 2 {
+			r.Header.Set(header[0], header[1])
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w
+	}
+
+	w := do(http.MethodGet, "/a")
+	require.Equal(t, "/a 1", w.Body.String())
+
+	w = do(http.MethodGet, "/a")
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "/a 1", w.Body.String())
+	require.Equal(t, "1", w.Header().Get("X-Call"))
+
+	require.Equal(t, "/a 2", do(http.MethodGet, "/a", "Cache-Control", "no-cache").Body.String())
+	require.Equal(t, "/a 3", do(http.MethodPost, "/a").Body.String())
+	require.Equal(t, "/a?x=1 4", do(http.MethodGet, "/a?x=1").Body.String())
+
+	do(http.MethodGet, "/private")
+	require.Equal(t, "/private 6", do(http.MethodGet, "/private").Body.String())
+
+	do(http.MethodGet, "/missing")
+	w = do(http.MethodGet, "/missing")
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "/missing 7", w.Body.String())
+
+	do(http.MethodGet, "/error")
+	require.Equal(t, "/error 9", do(http.MethodGet, "/error").Body.String())
+
+	do(http.MethodGet, "/long")
+
+	ttl, ok := c.TTL("GET example.com/long")
+	require.True(t, ok)
+	require.InDelta(t, time.Minute, ttl, float64(time.Second))
+	require.True(t, c.Delete("GET example.com/long"))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestMiddlewareAuthorization(t *testing.T) {
+	c := mcache.New[string, httpcache.CachedResponse]()
+
+	h := httpcache.Middleware(c, time.Minute)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/public" {
+			w.Header().Set("Cache-Control", "public")
+		}
+
+		fmt.Fprintf(w, "%s for %q", r.URL.Path, r.Header.Get("Authorization"))
+	}))
+
+	do := func(target, auth string) string {
+		r := httptest.NewRequest(http.MethodGet, target, nil)
+		if auth != "" {
+			r.Header.Set("Authorization", auth)
+		}
+
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+
+		return w.Body.String()
+	}
+
+	// Responses to authorized requests are not shared
+	require.Equal(t, `/me for "alice"`, do("/me", "alice"))
+	require.Equal(t, `/me for "bob"`, do("/me", "bob"))
+	require.Equal(t, `/me for ""`, do("/me", ""))
+
+	// Responses stored for anonymous requests are not served to authorized ones
+	require.Equal(t, `/me for ""`, do("/me", ""))
+	require.Equal(t, `/me for "bob"`, do("/me", "bob"))
+
+	// Unless they are explicitly public
+	require.Equal(t, `/public for "alice"`, do("/public", "alice"))
+	require.Equal(t, `/public for "alice"`, do("/public", "bob"))
+	require.Equal(t, `/public for "alice"`, do("/public", ""))
+}
+
+func TestTTL(t *testing.T) {
+	testCases := []struct {
+		name   string
+		status int
+		header http.Header
+		ttl    time.Duration
+		ok     bool
+	}{
+		{name: "default", status: http.StatusOK, header: http.Header{}, ttl: time.Minute, ok: true},
+		{name: "max-age", status: http.StatusOK, header: http.Header{"Cache-Control": {"max-age=10"}}, ttl: 10 * time.Second, ok: true},
+		{name: "s-maxage", status: http.StatusOK, header: http.Header{"Cache-Control": {"s-maxage=20, max-age=10"}}, ttl: 20 * time.Second, ok: true},
+		{name: "zero max-age", status: http.StatusOK, header: http.Header{"Cache-Control": {"max-age=0"}}},
+		{name: "no-store", status: http.StatusOK, header: http.Header{"Cache-Control": {"No-Store"}}},
+		{name: "cookie", status: http.StatusOK, header: http.Header{"Set-Cookie": {"a=b"}}},
+		{name: "vary", status: http.StatusOK, header: http.Header{"Vary": {"Accept"}}},
+		{name: "error", status: http.StatusBadGateway, header: http.Header{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ttl, ok := httpcache.TTL(tc.status, tc.header, time.Minute)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.ttl, ttl)
+		})
+	}
+}
@@ -0,0 +1,100 @@
+package httpcache
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+)
+
+// Transport is an http.RoundTripper caching responses to GET and HEAD requests,
+// following the same rules as Middleware.
+type Transport struct {
+	// Cache stores the responses
+	Cache *mcache.Cache[string, CachedResponse]
+	// Base makes the actual requests, http.DefaultTransport is used if nil
+	Base http.RoundTripper
+	// TTL is used for responses without max-age, zero TTL caches only responses with explicit max-age
+	TTL time.Duration
+	// StaleIfError serves expired responses when the request fails or the server responds with 5xx status code.
+	// Expired responses are only kept if the cache is created with WithGracePeriod.
+	StaleIfError bool
+}
+
+// RoundTrip serves the request from the cache, or makes it with the base transport caching the response.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !cacheableRequest(req) {
+		return t.base().RoundTrip(req)
+	}
+
+	key := Key(req)
+
+	cached, ok, expired := t.Cache.GetStale(key)
+	ok = ok && shareable(req, cached.Header)
+
+	if ok && !expired {
+		return cached.response(req), nil
+	}
+
+	resp, err := t.base().RoundTrip(req)
+	if ok && t.StaleIfError && (err != nil || resp.StatusCode >= http.StatusInternalServerError) {
+		if err == nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+
+		return cached.response(req), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	ttl, cacheable := TTL(resp.StatusCode, resp.Header, t.TTL)
+	if !cacheable || !shareable(req, resp.Header) {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	if err != nil {
+		return nil, err
+	}
+
+	t.Cache.Set(key, CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}, ttl)
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	return resp, nil
+}
+
+func (t *Transport) base() http.RoundTripper {
+	if t.Base != nil {
+		return t.Base
+	}
+
+	return http.DefaultTransport
+}
+
+// response makes a client response out of the cached one.
+func (r CachedResponse) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       req,
+	}
+}
@@ -0,0 +1,179 @@
+package httpcache_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/httpcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (fn roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
+
+func TestTransport(t *testing.T) {
+	c := mcache.New[string, httpcache.CachedResponse](mcache.WithGracePeriod(100 * time.Millisecond))
+
+	var (
+		calls int
+		fail  error
+		code  = http.StatusOK
+	)
+
+	client := &http.Client{
+		Transport: &httpcache.Transport{
+			Cache: c,
+			Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				calls++
+
+				if fail != nil {
+					return nil, fail
+				}
+
+				return &http.Response{
+					StatusCode: code,
+					Header:     http.Header{"Cache-Control": {"max-age=0"}},
+					Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("%s %d", r.URL, calls))),
+					Request:    r,
+				}, nil
+			}),
+			TTL:          30 * time.Millisecond,
+			StaleIfError: true,
+		},
+	}
+
+	get := func(method, url string) (string, error) {
+		req, _ := http.NewRequest(method, url, nil)
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return "", err
+		}
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+
+		return fmt.Sprintf("%d %s", resp.StatusCode, body), err
+	}
+
+	// max-age=0 is not cacheable
+	body, err := get(http.MethodGet, "http://example.com/a")
+	require.NoError(t, err)
+	require.Equal(t, "200 http://example.com/a 1", body)
+	require.Zero(t, c.Len())
+
+	// Now use the default TTL
+	client.Transport.(*httpcache.Transport).Base = roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		calls++
+
+		if fail != nil {
+			return nil, fail
+		}
+
+		return &http.Response{
+			StatusCode: code,
+			Header:     http.Header{},
+			Body:       io.NopCloser(strings.NewReader(fmt.Sprintf("%s %d", r.URL, calls))),
+			Request:    r,
+		}, nil
+	})
+
+	body, _ = get(http.MethodGet, "http://example.com/a")
+	require.Equal(t, "200 http://example.com/a 2", body)
+
+	body, _ = get(http.MethodGet, "http://example.com/a")
+	require.Equal(t, "200 http://example.com/a 2", body)
+
+	body, _ = get(http.MethodPost, "http://example.com/a")
+	require.Equal(t, "200 http://example.com/a 3", body)
+
+	time.Sleep(40 * time.Millisecond)
+
+	// Expired, but served as stale on errors
+	fail = errors.New("connection refused")
+
+	body, err = get(http.MethodGet, "http://example.com/a")
+	require.NoError(t, err)
+	require.Equal(t, "200 http://example.com/a 2", body)
+
+	fail, code = nil, http.StatusBadGateway
+
+	body, _ = get(http.MethodGet, "http://example.com/a")
+	require.Equal(t, "200 http://example.com/a 2", body)
+
+	// Not cached at all
+	body, _ = get(http.MethodGet, "http://example.com/b")
+	require.Equal(t, "502 http://example.com/b 6", body)
+
+	fail = errors.New("connection refused")
+
+	_, err = get(http.MethodGet, "http://example.com/b")
+	require.ErrorIs(t, err, fail)
+
+	fail, code = nil, http.StatusOK
+
+	body, _ = get(http.MethodGet, "http://example.com/a")
+	require.Equal(t, "200 http://example.com/a 8", body)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 300*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestTransportAuthorization(t *testing.T) {
+	c := mcache.New[string, httpcache.CachedResponse]()
+
+	client := &http.Client{
+		Transport: &httpcache.Transport{
+			Cache: c,
+			Base: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				header := http.Header{}
+				if r.URL.Path == "/shared" {
+					header.Set("Cache-Control", "s-maxage=60")
+				}
+
+				return &http.Response{
+					StatusCode: http.StatusOK,
+					Header:     header,
+					Body:       io.NopCloser(strings.NewReader(r.Header.Get("Authorization"))),
+					Request:    r,
+				}, nil
+			}),
+			TTL: time.Minute,
+		},
+	}
+
+	get := func(url, auth string) string {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", auth)
+
+		resp, err := client.Do(req)
+		require.NoError(t, err)
+
+		defer resp.Body.Close()
+
+		body, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+
+		return string(body)
+	}
+
+	require.Equal(t, "alice", get("http://example.com/me", "alice"))
+	require.Equal(t, "bob", get("http://example.com/me", "bob"))
+	require.Zero(t, c.Len())
+
+	require.Equal(t, "alice", get("http://example.com/shared", "alice"))
+	require.Equal(t, "alice", get("http://example.com/shared", "bob"))
+	require.Equal(t, 1, c.Len())
+}
@@ -0,0 +1,103 @@
+package mcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// EntryInfo holds access metadata of a cached item, tracked with WithEntryInfo.
+type EntryInfo struct {
+	Created  time.Time // When the key was stored, replacing the value keeps it
+	Updated  time.Time // When the value was last stored or changed
+	Accessed time.Time // When the value was last read, zero if never
+	Hits     uint64    // Number of times the value was read
+}
+
+// entryInfo is the access metadata attached to a queue item
+type entryInfo struct {
+	created  time.Time // Changed under the write lock
+	updated  time.Time
+	accessed atomic.Int64 // Changed under the read lock
+	hits     atomic.Uint64
+}
+
+// GetEntryInfo returns access metadata of the key. Returns false if the key is not found,
+// or metadata is not tracked because the cache was created without WithEntryInfo.
+func (c *Cache[K, V]) GetEntryInfo(key K) (EntryInfo, bool) {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) || v.Ptr.Info == nil {
+		return EntryInfo{}, false
+	}
+
+	info := EntryInfo{
+		Created: v.Ptr.Info.created,
+		Updated: v.Ptr.Info.updated,
+		Hits:    v.Ptr.Info.hits.Load(),
+	}
+
+	if accessed := v.Ptr.Info.accessed.Load(); accessed != 0 {
+		info.Accessed = time.Unix(0, accessed)
+	}
+
+	return info, true
+}
+
+// clone returns a copy of the metadata, nil if there is none.
+func (e *entryInfo) clone() *entryInfo {
+	if e == nil {
+		return nil
+	}
+
+	n := &entryInfo{
+		created: e.created,
+		updated: e.updated,
+	}
+
+	n.accessed.Store(e.accessed.Load())
+	n.hits.Store(e.hits.Load())
+
+	return n
+}
+
+// track attaches metadata to the new item, keeping the creation time of the replaced one.
+func (c *Cache[K, V]) track(i, replaced *item[K]) {
+	if !c.opts.entryInfo {
+		return
+	}
+
+	now := c.now()
+
+	i.Info = &entryInfo{
+		created: now,
+		updated: now,
+	}
+
+	if replaced != nil && replaced.Info != nil {
+		i.Info.created = replaced.Info.created
+	}
+}
+
+// updated records the item value change.
+func (c *Cache[K, V]) updated(i *item[K]) {
+	if i.Info != nil {
+		i.Info.updated = c.now()
+	}
+}
+
+// accessed records the item read, it is safe to call under the read lock.
+func (c *Cache[K, V]) accessed(i *item[K]) {
+	if i.Info != nil {
+		i.Info.access(c.now())
+	}
+}
+
+// access records the read, it is safe to call without locking.
+func (e *entryInfo) access(now time.Time) {
+	if e != nil {
+		e.accessed.Store(now.UnixNano())
+		e.hits.Add(1)
+	}
+}
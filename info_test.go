@@ -0,0 +1,55 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGetEntryInfo(t *testing.T) {
+	c := mcache.New[int, int](mcache.WithEntryInfo())
+
+	c.Set(1, 1, 50*time.Millisecond)
+
+	info, ok := c.GetEntryInfo(1)
+	require.True(t, ok)
+	require.Equal(t, info.Created, info.Updated)
+	require.True(t, info.Accessed.IsZero())
+	require.Zero(t, info.Hits)
+
+	time.Sleep(time.Millisecond)
+
+	c.Get(1)
+	c.MGet(1)
+	c.Set(1, 2, 50*time.Millisecond)
+	c.Get(1)
+
+	updated, ok := c.GetEntryInfo(1)
+	require.True(t, ok)
+	require.Equal(t, info.Created, updated.Created)
+	require.True(t, updated.Updated.After(info.Updated))
+	require.False(t, updated.Accessed.Before(updated.Updated))
+	require.EqualValues(t, 1, updated.Hits)
+
+	clone := c.Clone()
+
+	cloned, ok := clone.GetEntryInfo(1)
+	require.True(t, ok)
+	require.Equal(t, updated, cloned)
+
+	_, ok = c.GetEntryInfo(2)
+	require.False(t, ok)
+
+	plain := mcache.New[int, int]()
+	plain.Set(1, 1, 10*time.Millisecond)
+
+	_, ok = plain.GetEntryInfo(1)
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+clone.Len()+plain.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
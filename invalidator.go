@@ -0,0 +1,23 @@
+package mcache
+
+// Invalidator is a bus delivering invalidated keys between processes holding their own caches,
+// e.g. an adapter for Redis pub/sub or NATS.
+type Invalidator[K comparable] interface {
+	// Publish sends the key to other subscribers of the bus.
+	Publish(key K) error
+	// Subscribe makes the bus call fn for every key published by others, until the returned function is called.
+	Subscribe(fn func(key K)) (unsubscribe func())
+}
+
+// Invalidate deletes the key and publishes it to the invalidator set with WithInvalidator,
+// so other caches subscribed to the same bus delete it too.
+// Returns the error from the bus, the key is deleted locally regardless.
+func (c *Cache[K, V]) Invalidate(key K) error {
+	c.Delete(key)
+
+	if c.invalidator == nil {
+		return nil
+	}
+
+	return c.invalidator.Publish(key)
+}
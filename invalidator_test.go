@@ -0,0 +1,93 @@
+package mcache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// bus delivers published keys to all subscribers synchronously
+type bus struct {
+	m    sync.Mutex
+	subs map[int]func(string)
+	next int
+}
+
+type busClient struct {
+	bus *bus
+	id  int
+}
+
+func (b *bus) client() *busClient {
+	b.m.Lock()
+	defer b.m.Unlock()
+
+	b.next++
+
+	return &busClient{bus: b, id: b.next}
+}
+
+func (c *busClient) Publish(key string) error {
+	c.bus.m.Lock()
+	defer c.bus.m.Unlock()
+
+	for id, fn := range c.bus.subs {
+		if id != c.id {
+			fn(key)
+		}
+	}
+
+	return nil
+}
+
+func (c *busClient) Subscribe(fn func(string)) func() {
+	c.bus.m.Lock()
+	defer c.bus.m.Unlock()
+
+	if c.bus.subs == nil {
+		c.bus.subs = make(map[int]func(string))
+	}
+
+	c.bus.subs[c.id] = fn
+
+	return func() {
+		c.bus.m.Lock()
+		defer c.bus.m.Unlock()
+
+		delete(c.bus.subs, c.id)
+	}
+}
+
+func TestInvalidator(t *testing.T) {
+	b := &bus{}
+
+	c1 := mcache.New[string, int](mcache.WithInvalidator[string](b.client()))
+	c2 := mcache.New[string, int](mcache.WithInvalidator[string](b.client()))
+
+	c1.Set("a", 1, 20*time.Millisecond)
+	c2.Set("a", 1, 20*time.Millisecond)
+	c2.Set("b", 2, 20*time.Millisecond)
+
+	require.NoError(t, c1.Invalidate("a"))
+	require.False(t, c1.Has("a"))
+	require.False(t, c2.Has("a"))
+
+	c2.Close()
+
+	c1.Set("b", 2, 20*time.Millisecond)
+
+	require.NoError(t, c1.Invalidate("b"))
+	require.True(t, c2.Has("b"))
+
+	require.Panics(t, func() {
+		mcache.New[int, int](mcache.WithInvalidator[string](b.client()))
+	})
+
+	assert.Eventually(t, func() bool {
+		return 0 == c1.Len()+c2.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
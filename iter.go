@@ -0,0 +1,28 @@
+//go:build go1.23
+
+package mcache
+
+import "iter"
+
+// All returns iterator over key/value pairs in the order of eviction, with the same semantics as Range.
+func (c *Cache[K, V]) All() iter.Seq2[K, V] {
+	return c.Range
+}
+
+// KeysSeq returns iterator over keys in the order of eviction.
+func (c *Cache[K, V]) KeysSeq() iter.Seq[K] {
+	return func(yield func(K) bool) {
+		c.Range(func(key K, _ V) bool {
+			return yield(key)
+		})
+	}
+}
+
+// ValuesSeq returns iterator over values in the order of eviction.
+func (c *Cache[K, V]) ValuesSeq() iter.Seq[V] {
+	return func(yield func(V) bool) {
+		c.Range(func(_ K, value V) bool {
+			return yield(value)
+		})
+	}
+}
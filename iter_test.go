@@ -0,0 +1,51 @@
+//go:build go1.23
+
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIterators(t *testing.T) {
+	c := mcache.New[int, string]()
+
+	c.Set(1, "1", 30*time.Millisecond)
+	c.Set(2, "2", 40*time.Millisecond)
+	c.Set(3, "3", 50*time.Millisecond)
+
+	var keys []int
+
+	for k, v := range c.All() {
+		assert.Equal(t, k, int(v[0]-'0'))
+		keys = append(keys, k)
+
+		if k == 2 {
+			break
+		}
+	}
+
+	require.Equal(t, []int{1, 2}, keys)
+
+	keys = nil
+	for k := range c.KeysSeq() {
+		keys = append(keys, k)
+	}
+
+	require.Equal(t, []int{1, 2, 3}, keys)
+
+	var values []string
+	for v := range c.ValuesSeq() {
+		values = append(values, v)
+	}
+
+	require.Equal(t, []string{"1", "2", "3"}, values)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
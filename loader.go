@@ -0,0 +1,141 @@
+package mcache
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// ErrNotFound is returned when the key is not found, by GetContext without a loader, and by backing stores.
+var ErrNotFound = errors.New("mcache: key not found")
+
+// GetContext works as Get, but passes the context to the loader set with WithLoader, and returns its error.
+// Returns ErrNotFound if the key is not found and no loader is set.
+func (c *Cache[K, V]) GetContext(ctx context.Context, key K) (V, error) {
+	if value, ok := c.lookup(key); ok {
+		return value, nil
+	}
+
+	if c.loader == nil {
+		var zero V
+
+		return zero, ErrNotFound
+	}
+
+	return c.load(ctx, key)
+}
+
+// load loads the missing value with the loader, sharing the load between concurrent callers.
+// With negative caching, keys recently not found are not loaded again.
+func (c *Cache[K, V]) load(ctx context.Context, key K) (V, error) {
+	if c.misses != nil {
+		if err, ok := c.misses.get(key); ok {
+			var zero V
+
+			return zero, err
+		}
+	}
+
+	var cost time.Duration
+
+	value, err := c.compute(key, func() (V, time.Duration, error) {
+		start := c.now()
+
+		value, ttl, err := c.loader(ctx, key)
+		if c.misses != nil && errors.Is(err, ErrNotFound) {
+			c.misses.Set(key, err, c.opts.negativeTTL)
+		}
+
+		cost = c.now().Sub(start)
+
+		return value, ttl, err
+	})
+
+	if err == nil && cost > 0 && c.opts.xfetch > 0 {
+		c.setCost(key, cost)
+	}
+
+	return value, err
+}
+
+// refreshAhead reloads the value in the background if it is past its refresh point and is not being loaded already.
+func (c *Cache[K, V]) refreshAhead(key K) {
+	c.m.RLock()
+
+	v, ok := c.cache[key]
+	due := ok && v.Ptr.TTL > 0 &&
+		!c.now().Before(v.Ptr.Expires.Add(-time.Duration((1-c.opts.refreshAhead)*float64(v.Ptr.TTL))))
+
+	c.m.RUnlock()
+
+	if !due {
+		return
+	}
+
+	f, leader := c.join(key)
+	if !leader {
+		return
+	}
+
+	go c.reload(key, f)
+}
+
+// xfetch reloads the value if it draws the early expiration and is not being loaded already,
+// returning the reloaded value, or the given one otherwise.
+func (c *Cache[K, V]) xfetch(key K, value V) V {
+	c.m.RLock()
+
+	v, ok := c.cache[key]
+	early := ok && v.Ptr.Cost > 0 &&
+		!c.now().Add(time.Duration(-float64(v.Ptr.Cost)*c.opts.xfetch*math.Log(1-rand.Float64()))).Before(v.Ptr.Expires)
+
+	c.m.RUnlock()
+
+	if !early {
+		return value
+	}
+
+	f, leader := c.join(key)
+	if !leader {
+		return value
+	}
+
+	c.reload(key, f)
+
+	if f.err != nil {
+		return value
+	}
+
+	return f.value
+}
+
+// reload loads the value with the loader and stores it, finishing the computation of the key.
+func (c *Cache[K, V]) reload(key K, f *flight[V]) {
+	defer c.land(key, f)
+
+	start := c.now()
+
+	var ttl time.Duration
+
+	f.value, ttl, f.err = c.loader(context.Background(), key)
+	if f.err == nil {
+		c.Set(key, f.value, ttl)
+
+		if c.opts.xfetch > 0 {
+			c.setCost(key, c.now().Sub(start))
+		}
+	}
+}
+
+// setCost records how long the value of the key took to load.
+func (c *Cache[K, V]) setCost(key K, cost time.Duration) {
+	c.m.Lock()
+
+	if v, ok := c.cache[key]; ok {
+		v.Ptr.Cost = cost
+	}
+
+	c.m.Unlock()
+}
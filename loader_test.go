@@ -0,0 +1,242 @@
+package mcache_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoader(t *testing.T) {
+	var (
+		calls  int32
+		failed = errors.New("failed")
+	)
+
+	c := mcache.New[int, string](mcache.WithLoader(func(ctx context.Context, key int) (string, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+
+		if key < 0 {
+			return "", 0, failed
+		}
+
+		return strconv.Itoa(key), 20 * time.Millisecond, nil
+	}))
+
+	value, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "1", value)
+
+	value, ok = c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "1", value)
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	_, ok = c.Get(-1)
+	require.False(t, ok)
+	require.False(t, c.Has(-1))
+
+	_, err := c.GetContext(context.Background(), -1)
+	require.ErrorIs(t, err, failed)
+
+	value, err = c.GetContext(context.Background(), 2)
+	require.NoError(t, err)
+	require.Equal(t, "2", value)
+
+	// Existing values are served without the loader
+	c.Set(3, "three", 20*time.Millisecond)
+
+	value, err = c.GetContext(context.Background(), 3)
+	require.NoError(t, err)
+	require.Equal(t, "three", value)
+	require.EqualValues(t, 4, atomic.LoadInt32(&calls))
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestLoaderConcurrent(t *testing.T) {
+	var (
+		calls   int32
+		wg      sync.WaitGroup
+		release = make(chan struct{})
+	)
+
+	c := mcache.New[string, int](mcache.WithLoader(func(ctx context.Context, key string) (int, time.Duration, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+
+		return 42, 10 * time.Millisecond, nil
+	}))
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			value, ok := c.Get("a")
+			assert.True(t, ok)
+			assert.Equal(t, 42, value)
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWithoutLoader(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	_, err := c.GetContext(context.Background(), "a")
+	require.ErrorIs(t, err, mcache.ErrNotFound)
+
+	require.Panics(t, func() {
+		mcache.New[string, string](mcache.WithLoader(func(context.Context, string) (int, time.Duration, error) {
+			return 0, 0, nil
+		}))
+	})
+}
+
+func TestRefreshAhead(t *testing.T) {
+	var version int32
+
+	c := mcache.New[string, int32](
+		mcache.WithLoader(func(ctx context.Context, key string) (int32, time.Duration, error) {
+			return atomic.AddInt32(&version, 1), 40 * time.Millisecond, nil
+		}),
+		mcache.WithRefreshAhead(0.5),
+	)
+
+	value, ok := c.Get("a")
+	require.True(t, ok)
+	require.EqualValues(t, 1, value)
+
+	// Before the refresh point the value is served as is
+	value, ok = c.Get("a")
+	require.True(t, ok)
+	require.EqualValues(t, 1, value)
+
+	time.Sleep(25 * time.Millisecond)
+
+	// Past the refresh point the stale value is served while the new one is loaded
+	value, ok = c.Get("a")
+	require.True(t, ok)
+	require.EqualValues(t, 1, value)
+
+	assert.Eventually(t, func() bool {
+		value, ok := c.Get("a")
+
+		return ok && value == 2
+	}, 50*time.Millisecond, time.Millisecond)
+
+	ttl, ok := c.TTL("a")
+	require.True(t, ok)
+	require.Greater(t, ttl, 30*time.Millisecond)
+	require.EqualValues(t, 2, atomic.LoadInt32(&version))
+
+	c.Clear()
+}
+
+func TestNegativeTTL(t *testing.T) {
+	var calls int32
+
+	c := mcache.New[string, int](
+		mcache.WithLoader(func(ctx context.Context, key string) (int, time.Duration, error) {
+			atomic.AddInt32(&calls, 1)
+
+			if key == "missing" {
+				return 0, 0, fmt.Errorf("no %s: %w", key, mcache.ErrNotFound)
+			}
+
+			return 0, 0, errors.New("failed")
+		}),
+		mcache.WithNegativeTTL(20*time.Millisecond),
+	)
+
+	for i := 0; i < 3; i++ {
+		_, err := c.GetContext(context.Background(), "missing")
+		require.ErrorIs(t, err, mcache.ErrNotFound)
+		require.EqualError(t, err, "no missing: mcache: key not found")
+
+		_, ok := c.Get("missing")
+		require.False(t, ok)
+	}
+
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	// Other errors are not remembered
+	_, ok := c.Get("broken")
+	require.False(t, ok)
+
+	_, ok = c.Get("broken")
+	require.False(t, ok)
+	require.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	// Stored values take precedence
+	c.Set("missing", 1, 10*time.Millisecond)
+
+	value, ok := c.Get("missing")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	time.Sleep(25 * time.Millisecond)
+
+	_, ok = c.Get("missing")
+	require.False(t, ok)
+	require.EqualValues(t, 4, atomic.LoadInt32(&calls))
+
+	c.Clear()
+}
+
+func TestXFetch(t *testing.T) {
+	var version int32
+
+	c := mcache.New[string, int32](
+		mcache.WithLoader(func(ctx context.Context, key string) (int32, time.Duration, error) {
+			time.Sleep(2 * time.Millisecond)
+
+			return atomic.AddInt32(&version, 1), 20 * time.Millisecond, nil
+		}),
+		mcache.WithXFetch(1e4),
+	)
+
+	value, ok := c.Get("a")
+	require.True(t, ok)
+	require.EqualValues(t, 1, value)
+
+	// With the huge beta the value is always considered close to expiration
+	value, ok = c.Get("a")
+	require.True(t, ok)
+	require.EqualValues(t, 2, value)
+
+	value, ok = c.Get("a")
+	require.True(t, ok)
+	require.EqualValues(t, 3, value)
+
+	// Values not loaded by the loader are not reloaded early
+	c.Set("b", 0, 20*time.Millisecond)
+
+	value, ok = c.Get("b")
+	require.True(t, ok)
+	require.EqualValues(t, 0, value)
+	require.EqualValues(t, 3, atomic.LoadInt32(&version))
+
+	c.Clear()
+}
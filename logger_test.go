@@ -0,0 +1,66 @@
+package mcache_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testLogger struct {
+	m      sync.Mutex
+	events []string
+}
+
+func (l *testLogger) Debug(msg string, args ...any) {
+	l.m.Lock()
+	l.events = append(l.events, strings.TrimSpace(fmt.Sprintln(append([]any{msg}, args...)...)))
+	l.m.Unlock()
+}
+
+func (l *testLogger) Has(prefix string) bool {
+	l.m.Lock()
+	defer l.m.Unlock()
+
+	for _, event := range l.events {
+		if strings.HasPrefix(event, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestWithLogger(t *testing.T) {
+	l := &testLogger{}
+
+	c := mcache.New[string, int](
+		mcache.WithLogger(l),
+		mcache.WithEvictionCallback(func(key string, _ int, _ mcache.Reason) {
+			if key == "boom" {
+				panic("callback failed")
+			}
+		}),
+	)
+
+	c.Set("a", 1, 10*time.Millisecond)
+	c.Set("boom", 2, 20*time.Millisecond)
+	c.Set("b", 3, 30*time.Millisecond)
+
+	require.True(t, l.Has("mcache: expiration timer reset key a"))
+
+	require.Equal(t, 1, c.Evict(1))
+	require.True(t, l.Has("mcache: items evicted count 1"))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	require.True(t, l.Has("mcache: item expired key boom"))
+	require.True(t, l.Has("mcache: eviction callback panicked key boom reason expired panic callback failed"))
+}
@@ -0,0 +1,18 @@
+package mcache
+
+import "time"
+
+// Memoize wraps the function with a cache, keeping its results for ttl. Errors are not cached.
+// Concurrent calls with the same key share a single call of fn, as with GetOrCompute.
+// Options configure the underlying cache.
+func Memoize[K comparable, V any](fn func(K) (V, error), ttl time.Duration, opts ...Option) func(K) (V, error) {
+	c := New[K, V](opts...)
+
+	return func(key K) (V, error) {
+		return c.GetOrCompute(key, func() (V, time.Duration, error) {
+			value, err := fn(key)
+
+			return value, ttl, err
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package mcache_test
+
+import (
+	"errors"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoize(t *testing.T) {
+	var calls int32
+
+	failed := errors.New("failed")
+
+	itoa := mcache.Memoize(func(n int) (string, error) {
+		atomic.AddInt32(&calls, 1)
+
+		if n < 0 {
+			return "", failed
+		}
+
+		return strconv.Itoa(n), nil
+	}, 10*time.Millisecond)
+
+	for i := 0; i < 3; i++ {
+		s, err := itoa(42)
+		require.NoError(t, err)
+		require.Equal(t, "42", s)
+	}
+
+	require.EqualValues(t, 1, atomic.LoadInt32(&calls))
+
+	// Errors are not cached
+	_, err := itoa(-1)
+	require.ErrorIs(t, err, failed)
+
+	_, err = itoa(-1)
+	require.ErrorIs(t, err, failed)
+	require.EqualValues(t, 3, atomic.LoadInt32(&calls))
+
+	time.Sleep(15 * time.Millisecond)
+
+	_, err = itoa(42)
+	require.NoError(t, err)
+	require.EqualValues(t, 4, atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,124 @@
+package mcache
+
+import (
+	"reflect"
+	"strings"
+	"time"
+)
+
+// Namespace is a view of a cache with string keys, prefixing keys with the namespace name,
+// so that subsystems sharing the cache do not collide. Keys are prefixed with the name and a colon.
+type Namespace[K comparable, V any] struct {
+	cache    *Cache[K, V]
+	prefix   string
+	toString func(K) string // Converts keys to strings, keys are always of string kind
+	toKey    func(string) K
+}
+
+var _ Cacher[string, int] = (*Namespace[string, int])(nil)
+
+// NewNamespace creates a view of the cache scoped to the given namespace.
+func NewNamespace[K ~string, V any](c *Cache[K, V], name string) *Namespace[K, V] {
+	return &Namespace[K, V]{
+		cache:    c,
+		prefix:   name + ":",
+		toString: func(key K) string { return string(key) },
+		toKey:    func(s string) K { return K(s) },
+	}
+}
+
+// Namespace creates a view of the cache scoped to the given namespace, as NewNamespace does.
+// It panics if the cache keys are not strings, NewNamespace checks that at compile time.
+func (c *Cache[K, V]) Namespace(name string) *Namespace[K, V] {
+	t := reflect.TypeOf((*K)(nil)).Elem()
+	if t.Kind() != reflect.String {
+		panic("mcache: Namespace requires cache keys of string type")
+	}
+
+	return &Namespace[K, V]{
+		cache:    c,
+		prefix:   name + ":",
+		toString: func(key K) string { return reflect.ValueOf(&key).Elem().String() },
+		toKey:    func(s string) K { return reflect.ValueOf(s).Convert(t).Interface().(K) },
+	}
+}
+
+// Namespace creates a view nested in this namespace.
+func (n *Namespace[K, V]) Namespace(name string) *Namespace[K, V] {
+	return &Namespace[K, V]{
+		cache:    n.cache,
+		prefix:   n.prefix + name + ":",
+		toString: n.toString,
+		toKey:    n.toKey,
+	}
+}
+
+// Set adds or replaces a value with key and given TTL.
+func (n *Namespace[K, V]) Set(key K, value V, ttl time.Duration) {
+	n.cache.Set(n.key(key), value, ttl)
+}
+
+// Get returns value from the cache.
+func (n *Namespace[K, V]) Get(key K) (V, bool) {
+	return n.cache.Get(n.key(key))
+}
+
+// Has reports whether the key is present in the cache.
+func (n *Namespace[K, V]) Has(key K) bool {
+	return n.cache.Has(n.key(key))
+}
+
+// Delete deletes the key from the cache, reporting whether it was present.
+func (n *Namespace[K, V]) Delete(key K) bool {
+	return n.cache.Delete(n.key(key))
+}
+
+// Refresh sets new TTL for the key, reporting whether the key was present.
+func (n *Namespace[K, V]) Refresh(key K, ttl time.Duration) bool {
+	return n.cache.Refresh(n.key(key), ttl)
+}
+
+// Len returns number of items in the namespace. It scans the whole cache.
+func (n *Namespace[K, V]) Len() (count int) {
+	n.cache.Range(func(key K, _ V) bool {
+		if n.contains(key) {
+			count++
+		}
+
+		return true
+	})
+
+	return
+}
+
+// Keys returns keys of the namespace without the prefix, in the order of eviction. It scans the whole cache.
+func (n *Namespace[K, V]) Keys() []K {
+	var keys []K
+
+	n.cache.Range(func(key K, _ V) bool {
+		if n.contains(key) {
+			keys = append(keys, n.toKey(n.toString(key)[len(n.prefix):]))
+		}
+
+		return true
+	})
+
+	return keys
+}
+
+// Clear removes all items of the namespace, including nested namespaces, leaving other items intact.
+func (n *Namespace[K, V]) Clear() int {
+	return n.cache.DeleteWhere(func(key K, _ V) bool {
+		return n.contains(key)
+	})
+}
+
+// key returns the cache key of the namespace key.
+func (n *Namespace[K, V]) key(key K) K {
+	return n.toKey(n.prefix + n.toString(key))
+}
+
+// contains reports whether the cache key belongs to the namespace.
+func (n *Namespace[K, V]) contains(key K) bool {
+	return strings.HasPrefix(n.toString(key), n.prefix)
+}
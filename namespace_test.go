@@ -0,0 +1,81 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNamespace(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	sessions := mcache.NewNamespace(c, "sessions")
+	users := mcache.NewNamespace(c, "users")
+	admins := users.Namespace("admins")
+
+	sessions.Set("a", 1, 20*time.Millisecond)
+	sessions.Set("b", 2, 20*time.Millisecond)
+	users.Set("a", 3, 20*time.Millisecond)
+	admins.Set("a", 4, 20*time.Millisecond)
+	c.Set("a", 5, 20*time.Millisecond)
+
+	value, ok := sessions.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	value, ok = users.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 3, value)
+
+	value, ok = c.Get("users:admins:a")
+	require.True(t, ok)
+	require.Equal(t, 4, value)
+
+	require.Equal(t, 2, sessions.Len())
+	require.ElementsMatch(t, []string{"a", "b"}, sessions.Keys())
+	require.ElementsMatch(t, []string{"a", "admins:a"}, users.Keys())
+
+	require.True(t, sessions.Refresh("a", 10*time.Millisecond))
+	require.True(t, sessions.Delete("b"))
+	require.False(t, sessions.Has("b"))
+
+	// Clear only touches the namespace and the nested ones
+	require.Equal(t, 2, users.Clear())
+	require.False(t, admins.Has("a"))
+	require.True(t, sessions.Has("a"))
+	require.Equal(t, 2, c.Len())
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+type sessionID string
+
+func TestCacheNamespace(t *testing.T) {
+	c := mcache.New[sessionID, int]()
+
+	sessions := c.Namespace("sessions")
+	admins := sessions.Namespace("admins")
+
+	sessions.Set("a", 1, time.Minute)
+	admins.Set("a", 2, time.Minute)
+	c.Set("a", 3, time.Minute)
+
+	value, ok := c.Get("sessions:admins:a")
+	require.True(t, ok)
+	require.Equal(t, 2, value)
+
+	require.ElementsMatch(t, []sessionID{"a", "admins:a"}, sessions.Keys())
+	require.Equal(t, 1, admins.Clear())
+	require.Equal(t, 2, c.Len())
+
+	users := mcache.New[string, int]()
+	users.Namespace("users").Set("a", 4, time.Minute)
+	require.True(t, users.Has("users:a"))
+
+	require.Panics(t, func() { mcache.New[int, int]().Namespace("numbers") })
+}
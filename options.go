@@ -0,0 +1,323 @@
+package mcache
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+// Option configures a cache instance.
+type Option func(*options)
+
+// Precision defines how accurately items expire.
+type Precision int
+
+const (
+	// Precise items are removed exactly when they expire.
+	Precise Precision = iota
+	// Relaxed items are removed at the next sweep interval boundary after they expire,
+	// so items expiring close to each other are removed together.
+	Relaxed
+)
+
+// Reason tells why the item was removed from the cache.
+type Reason int
+
+const (
+	// Expired items have reached their TTL.
+	Expired Reason = iota
+	// Evicted items were removed with Evict.
+	Evicted
+	// Cleared items were removed with Clear.
+	Cleared
+)
+
+func (r Reason) String() string {
+	switch r {
+	case Expired:
+		return "expired"
+	case Evicted:
+		return "evicted"
+	case Cleared:
+		return "cleared"
+	default:
+		return "unknown"
+	}
+}
+
+// Logger receives debug events, *slog.Logger satisfies it.
+type Logger interface {
+	Debug(msg string, args ...any)
+}
+
+const defaultSweepInterval = time.Second
+
+type options struct {
+	grace     time.Duration // How long expired items are kept to be served as stale
+	precision Precision     // Default expiration precision
+	sweep     time.Duration // Expiration granularity for relaxed items
+	ttl       time.Duration // Default TTL for items stored without explicit TTL
+	equal     any           // Value equality function, func(V, V) bool
+	onEvict   any           // Eviction callback, func(K, V, Reason)
+	sliding   bool          // Get moves expiration forward
+	jitter    float64       // Fraction of TTL to randomize by
+	minTTL    time.Duration // TTLs given by caller are clamped to [minTTL, maxTTL]
+	maxTTL    time.Duration
+
+	snapshotEvery time.Duration // Interval of background snapshots
+	snapshotSink  SnapshotSink  // Destination of background snapshots
+	compress      bool          // Snapshots are gzip-compressed
+	entryInfo     bool          // Access metadata is tracked for every item
+	hotWindow     time.Duration // Window of hot key tracking
+	hotKeys       int           // Number of tracked hot keys
+	logger        Logger        // Receiver of debug events
+	events        int           // Buffer size of the events channel
+	invalidator   any           // Invalidation bus, Invalidator[K]
+	lockFreeReads bool          // Get reads from the lock-free index
+	sizer         any           // Memory estimation function, func(K, V) int
+	budget        int64         // Estimated memory limit
+	maxValueSize  int           // Values larger than this are not stored
+	valueSizer    any           // Value size function, func(V) int
+	oversized     any           // Handler of oversized values, func(K, V) (V, bool)
+	clock         Clock         // Source of time
+	loader        any           // Read-through loader, func(context.Context, K) (V, time.Duration, error)
+	refreshAhead  float64       // Fraction of TTL after which Get reloads values in the background
+	negativeTTL   time.Duration // How long keys not found by the loader are remembered
+	xfetch        float64       // XFetch beta, how eagerly values are reloaded before they expire
+}
+
+// reconfigure copies options that can be safely changed on a live cache.
+func (o *options) reconfigure(n options) {
+	o.grace = n.grace
+	o.precision = n.precision
+	o.sweep = n.sweep
+	o.ttl = n.ttl
+	o.jitter = n.jitter
+	o.minTTL = n.minTTL
+	o.maxTTL = n.maxTTL
+	o.logger = n.logger
+	o.budget = n.budget
+}
+
+// WithGracePeriod keeps expired items for additional period of time, so they can be obtained with GetStale.
+func WithGracePeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.grace = d
+	}
+}
+
+// WithPrecision sets default expiration precision for items stored with Set.
+func WithPrecision(p Precision) Option {
+	return func(o *options) {
+		o.precision = p
+	}
+}
+
+// WithSweepInterval sets the granularity relaxed items are expired with. Default is one second.
+func WithSweepInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.sweep = d
+	}
+}
+
+// WithDefaultTTL sets TTL used where no TTL is given explicitly: by Touch, and for values obtained with GetWithFallback.
+func WithDefaultTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// WithEqual sets function used to compare values by CompareAndSwap and similar methods.
+// By default values are compared with ==, which panics if values are not comparable.
+func WithEqual[V any](fn func(a, b V) bool) Option {
+	return func(o *options) {
+		o.equal = fn
+	}
+}
+
+// WithSizer sets function returning the number of bytes referenced by the key and the value, such as contents
+// of strings, slices, and maps, for EstimatedBytes. The size of the key and the value themselves is added by the cache.
+func WithSizer[K comparable, V any](fn func(key K, value V) int) Option {
+	return func(o *options) {
+		o.sizer = fn
+	}
+}
+
+// WithMemoryBudget makes the cache evict items expiring earliest whenever the memory estimated as by EstimatedBytes
+// exceeds the budget. The sizer is set as with WithSizer, nil sizer counts only contents of strings and byte slices.
+// An item larger than the budget is evicted right away, along with all items expiring earlier.
+func WithMemoryBudget[K comparable, V any](bytes int64, sizer func(key K, value V) int) Option {
+	return func(o *options) {
+		o.budget = bytes
+
+		if sizer != nil {
+			o.sizer = sizer
+		}
+	}
+}
+
+// WithMaxValueSize makes Set and similar methods reject values larger than the given number of bytes, as returned
+// by the sizer. A nil sizer measures strings and byte slices by length, values of other types are never rejected.
+// Storing a rejected value removes the previous value of the key, rejected values are counted in Stats.
+func WithMaxValueSize[V any](bytes int, sizer func(value V) int) Option {
+	return func(o *options) {
+		o.maxValueSize = bytes
+
+		if sizer != nil {
+			o.valueSizer = sizer
+		}
+	}
+}
+
+// WithOversizedValueHandler sets function called for values exceeding WithMaxValueSize instead of rejecting them.
+// The function returns the value to store instead, such as a truncated one, or false to reject the value.
+func WithOversizedValueHandler[K comparable, V any](fn func(key K, value V) (V, bool)) Option {
+	return func(o *options) {
+		o.oversized = fn
+	}
+}
+
+// WithClock makes the cache use the clock instead of the system one, so tests can control expiration.
+// See the clockmock package for a clock advanced manually.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		o.clock = clock
+	}
+}
+
+// WithLoader makes Get load missing values with the function and store them with the returned TTL.
+// Concurrent misses of the same key share a single load, as with GetOrCompute.
+// Get uses the background context and reports loading errors as not found, GetContext returns them.
+func WithLoader[K comparable, V any](fn func(ctx context.Context, key K) (V, time.Duration, error)) Option {
+	return func(o *options) {
+		o.loader = fn
+	}
+}
+
+// WithRefreshAhead makes Get reload the value with the loader in the background, once the given fraction
+// of the value TTL has passed, e.g. 0.8 reloads values stored for a minute after 48 seconds.
+// Frequently read values are then replaced before they expire. The option requires WithLoader.
+// The fraction is limited to [0, 1] range, zero disables refreshing.
+func WithRefreshAhead(fraction float64) Option {
+	return func(o *options) {
+		o.refreshAhead = math.Min(math.Max(fraction, 0), 1)
+	}
+}
+
+// WithNegativeTTL makes the cache remember keys the loader reports as not found, by returning an error
+// wrapping ErrNotFound, for the given TTL. Lookups of these keys fail with the same error without calling
+// the loader until the TTL passes, values stored meanwhile are served as usual. The option requires WithLoader.
+func WithNegativeTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.negativeTTL = ttl
+	}
+}
+
+// WithXFetch makes Get reload values before they expire with probability growing as they near expiration,
+// following the XFetch algorithm. Values that took longer to load are reloaded earlier, beta scales how early,
+// 1 being the usual choice. The caller that draws the early expiration reloads the value, while others get
+// the cached one, so reloads of popular keys are spread out without coordination. The option requires WithLoader,
+// and is ignored with WithRefreshAhead.
+func WithXFetch(beta float64) Option {
+	return func(o *options) {
+		o.xfetch = math.Max(beta, 0)
+	}
+}
+
+// WithEvictionCallback sets function called for every item removed from the cache other than by deletion.
+// The function is called outside the cache lock, so it may use the cache.
+func WithEvictionCallback[K comparable, V any](fn func(key K, value V, reason Reason)) Option {
+	return func(o *options) {
+		o.onEvict = fn
+	}
+}
+
+// WithSlidingTTL makes every successful Get move the key expiration forward by the TTL it was stored with.
+func WithSlidingTTL() Option {
+	return func(o *options) {
+		o.sliding = true
+	}
+}
+
+// WithTTLJitter randomizes TTL of stored items by up to the given fraction in both directions,
+// e.g. 0.1 makes TTL of one minute vary from 54 to 66 seconds. It helps to avoid many items expiring at once.
+// The fraction is limited to [0, 1] range. Deadlines given to SetUntil are kept exact.
+func WithTTLJitter(fraction float64) Option {
+	return func(o *options) {
+		o.jitter = math.Min(math.Max(fraction, 0), 1)
+	}
+}
+
+// WithTTLLimits clamps TTLs given when storing or refreshing items to [min, max] range, after any jitter.
+// Zero max means no upper limit. Deadlines given to SetUntil are not clamped.
+func WithTTLLimits(min, max time.Duration) Option {
+	return func(o *options) {
+		o.minTTL, o.maxTTL = min, max
+	}
+}
+
+// WithSnapshot makes the cache write the binary snapshot to the sink at the given interval, and once more on Close.
+// Snapshots copy the items under a read lock and are encoded without holding it, so readers are never blocked.
+// The interval is measured by the cache clock, see WithClock.
+func WithSnapshot(interval time.Duration, sink SnapshotSink) Option {
+	return func(o *options) {
+		o.snapshotEvery, o.snapshotSink = interval, sink
+	}
+}
+
+// WithCompressedSnapshots makes WriteSnapshot, SaveToFile, and background snapshots gzip-compressed.
+// ReadSnapshot and LoadFromFile detect compression automatically.
+func WithCompressedSnapshots() Option {
+	return func(o *options) {
+		o.compress = true
+	}
+}
+
+// WithEntryInfo enables tracking of creation, update, and access times, and hit counts of items,
+// which can be obtained with GetEntryInfo. It adds a small allocation to every stored item.
+func WithEntryInfo() Option {
+	return func(o *options) {
+		o.entryInfo = true
+	}
+}
+
+// WithHotKeyTracking makes the cache estimate how often keys are read with Get over the sliding window,
+// keeping track of up to capacity most frequent keys, which can be obtained with TopKeys.
+// Keys are tracked in shards with their own mutexes, so tracking adds little contention between Get calls.
+func WithHotKeyTracking(window time.Duration, capacity int) Option {
+	return func(o *options) {
+		o.hotWindow, o.hotKeys = window, capacity
+	}
+}
+
+// WithLogger makes the cache log expirations, evictions, expiration timer resets, and eviction callback panics
+// as debug events with key/value attributes. With a logger set, panics in the eviction callback are recovered.
+func WithLogger(l Logger) Option {
+	return func(o *options) {
+		o.logger = l
+	}
+}
+
+// WithEvents enables the Events channel with the given buffer size.
+func WithEvents(buffer int) Option {
+	return func(o *options) {
+		o.events = buffer
+	}
+}
+
+// WithLockFreeReads makes Get read from an index that needs no locking, so reads do not contend with each other
+// or with writers. Every write also updates the index, so writes become slower and use more memory.
+// It suits read-dominated workloads on many cores. The option has no effect with sliding expiration.
+func WithLockFreeReads() Option {
+	return func(o *options) {
+		o.lockFreeReads = true
+	}
+}
+
+// WithInvalidator subscribes the cache to the invalidation bus, deleting keys received from it.
+// Keys are published to the bus with Invalidate. The subscription is cancelled with Close.
+func WithInvalidator[K comparable](inv Invalidator[K]) Option {
+	return func(o *options) {
+		o.invalidator = inv
+	}
+}
@@ -0,0 +1,251 @@
+package mcache
+
+import (
+	"bufio"
+	"encoding/gob"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+)
+
+// snapshotEntry is a single persisted item
+type snapshotEntry[K comparable, V any] struct {
+	Key     K
+	Value   V
+	Expires time.Time
+}
+
+// Codec encodes and decodes single items for Save and Load, allowing any serialization format to be plugged in.
+// DecodeEntry must consume exactly one item from r and return io.EOF when there are no more items.
+type Codec[K comparable, V any] interface {
+	EncodeEntry(w io.Writer, key K, value V, expires time.Time) error
+	DecodeEntry(r io.Reader) (key K, value V, expires time.Time, err error)
+}
+
+// jsonEntry is a single item in JSON form
+type jsonEntry[K comparable, V any] struct {
+	Key     K         `json:"key"`
+	Value   V         `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+// Encode writes the cache contents to w using encoding/gob, keeping absolute expiration times.
+// Keys and values must be gob-serializable.
+func (c *Cache[K, V]) Encode(w io.Writer) error {
+	enc := gob.NewEncoder(w)
+
+	return c.stream(func(entry snapshotEntry[K, V]) error {
+		return enc.Encode(entry)
+	})
+}
+
+// Decode stores items read from r, written by Encode. Items that have already expired are skipped.
+// Items are stored in chunks as they are read, so on error some of them may have been stored.
+func (c *Cache[K, V]) Decode(r io.Reader) error {
+	dec := gob.NewDecoder(r)
+
+	var entries []snapshotEntry[K, V]
+
+	for {
+		var entry snapshotEntry[K, V]
+
+		if err := dec.Decode(&entry); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+
+		if entries = append(entries, entry); len(entries) == getManyChunk {
+			c.restore(entries)
+			entries = entries[:0]
+		}
+	}
+
+	c.restore(entries)
+
+	return nil
+}
+
+// Save writes the cache contents to w, encoding each item with the codec.
+func (c *Cache[K, V]) Save(w io.Writer, codec Codec[K, V]) error {
+	bw := bufio.NewWriter(w)
+
+	if err := c.stream(func(entry snapshotEntry[K, V]) error {
+		return codec.EncodeEntry(bw, entry.Key, entry.Value, entry.Expires)
+	}); err != nil {
+		return err
+	}
+
+	return bw.Flush()
+}
+
+// Load stores items read from r, decoding each item with the codec. Items that have already expired are skipped.
+// Items are stored in chunks as they are read, so on error some of them may have been stored.
+func (c *Cache[K, V]) Load(r io.Reader, codec Codec[K, V]) error {
+	br := bufio.NewReader(r)
+
+	var entries []snapshotEntry[K, V]
+
+	for {
+		key, value, expires, err := codec.DecodeEntry(br)
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+
+			return err
+		}
+
+		if entries = append(entries, snapshotEntry[K, V]{
+			Key:     key,
+			Value:   value,
+			Expires: expires,
+		}); len(entries) == getManyChunk {
+			c.restore(entries)
+			entries = entries[:0]
+		}
+	}
+
+	c.restore(entries)
+
+	return nil
+}
+
+// MarshalJSON encodes the cache contents as a JSON array of items with RFC 3339 expiration times.
+// Keys and values must be JSON-serializable.
+func (c *Cache[K, V]) MarshalJSON() ([]byte, error) {
+	entries := c.snapshot()
+	items := make([]jsonEntry[K, V], len(entries))
+
+	for i, entry := range entries {
+		items[i] = jsonEntry[K, V](entry)
+	}
+
+	return json.Marshal(items)
+}
+
+// UnmarshalJSON stores items from the JSON array written by MarshalJSON. Items that have already expired are skipped.
+// The cache must be created with New before unmarshalling.
+func (c *Cache[K, V]) UnmarshalJSON(data []byte) error {
+	var items []jsonEntry[K, V]
+
+	if err := json.Unmarshal(data, &items); err != nil {
+		return err
+	}
+
+	entries := make([]snapshotEntry[K, V], len(items))
+
+	for i, item := range items {
+		entries[i] = snapshotEntry[K, V](item)
+	}
+
+	c.restore(entries)
+
+	return nil
+}
+
+// snapshot returns live items in expiration order.
+func (c *Cache[K, V]) snapshot() []snapshotEntry[K, V] {
+	c.m.RLock()
+
+	entries := make([]snapshotEntry[K, V], 0, len(c.cache))
+
+	for n := c.head; n != nil; n = n.Next {
+		if c.alive(n) {
+			entries = append(entries, snapshotEntry[K, V]{
+				Key:     n.Key,
+				Value:   c.cache[n.Key].Value,
+				Expires: n.Expires,
+			})
+		}
+	}
+
+	c.m.RUnlock()
+
+	return entries
+}
+
+// stream calls fn for every live item in expiration order, without materializing the whole cache.
+// The read lock is held while copying a chunk of items, but not while fn is called, so writers are not blocked for long.
+// Items changed during streaming may be missed or passed twice, the latest version of a key is passed last.
+func (c *Cache[K, V]) stream(fn func(snapshotEntry[K, V]) error) error {
+	var (
+		cursor  *item[K] // The last copied item
+		expires time.Time
+		chunk   = make([]snapshotEntry[K, V], 0, getManyChunk)
+	)
+
+	for {
+		c.m.RLock()
+
+		n := c.head
+
+		if cursor != nil {
+			if v, ok := c.cache[cursor.Key]; ok && v.Ptr == cursor && cursor.Expires.Equal(expires) {
+				n = cursor.Next
+			} else {
+				// The cursor item was removed or moved, continue from its former place in the queue
+				for n != nil && n.Expires.Before(expires) {
+					n = n.Next
+				}
+			}
+		}
+
+		for ; n != nil && len(chunk) < getManyChunk; n = n.Next {
+			if c.alive(n) {
+				chunk = append(chunk, snapshotEntry[K, V]{
+					Key:     n.Key,
+					Value:   c.cache[n.Key].Value,
+					Expires: n.Expires,
+				})
+			}
+
+			cursor, expires = n, n.Expires
+		}
+
+		c.m.RUnlock()
+
+		for _, entry := range chunk {
+			if err := fn(entry); err != nil {
+				return err
+			}
+		}
+
+		if n == nil {
+			return nil
+		}
+
+		chunk = chunk[:0]
+	}
+}
+
+// restore stores the entries in a single pass, skipping already expired ones.
+func (c *Cache[K, V]) restore(entries []snapshotEntry[K, V]) {
+	now := c.now()
+
+	c.m.Lock()
+
+	head := c.head
+	items := make([]*item[K], 0, len(entries))
+
+	for _, entry := range entries {
+		if !entry.Expires.After(now) {
+			continue
+		}
+
+		if i := c.addMany(entry.Key, entry.Value, entry.Expires, true); i != nil {
+			items = append(items, i)
+		}
+	}
+
+	c.spliceMany(items)
+
+	if c.head != nil && c.head != head {
+		c.setTimer()
+	}
+
+	c.unlock()
+}
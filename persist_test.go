@@ -0,0 +1,116 @@
+package mcache_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 30*time.Millisecond)
+	c.Set("b", 2, 50*time.Millisecond)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.Encode(&buf))
+
+	d := mcache.New[string, int]()
+
+	require.NoError(t, d.Decode(bytes.NewReader(buf.Bytes())))
+	require.Equal(t, []string{"a", "b"}, d.Keys())
+	require.Equal(t, []int{1, 2}, d.Values())
+
+	_, expected, _ := c.GetWithExpiry("b")
+	_, expires, _ := d.GetWithExpiry("b")
+	require.True(t, expected.Equal(expires))
+
+	require.Error(t, mcache.New[int, int]().Decode(bytes.NewReader(buf.Bytes())))
+
+	time.Sleep(40 * time.Millisecond)
+
+	e := mcache.New[string, int]()
+
+	require.NoError(t, e.Decode(bytes.NewReader(buf.Bytes())))
+	require.Equal(t, []string{"b"}, e.Keys())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()+e.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestJSON(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.SetUntil("a", 1, time.Now().Add(30*time.Millisecond))
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	_, expires, _ := c.GetWithExpiry("a")
+	require.JSONEq(t, `[{"key":"a","value":1,"expires":"`+expires.Format(time.RFC3339Nano)+`"}]`, string(data))
+
+	d := mcache.New[string, int]()
+
+	require.NoError(t, json.Unmarshal(data, d))
+	require.Equal(t, []string{"a"}, d.Keys())
+	require.Error(t, json.Unmarshal([]byte(`{}`), d))
+
+	require.NoError(t, json.Unmarshal([]byte(`[{"key":"b","value":2,"expires":"2000-01-01T00:00:00Z"}]`), d))
+	require.Equal(t, 1, d.Len())
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+type lineCodec struct{}
+
+func (lineCodec) EncodeEntry(w io.Writer, key string, value int, expires time.Time) error {
+	_, err := fmt.Fprintf(w, "%s %d %d\n", key, value, expires.UnixNano())
+
+	return err
+}
+
+func (lineCodec) DecodeEntry(r io.Reader) (key string, value int, expires time.Time, err error) {
+	var nanos int64
+
+	if _, err = fmt.Fscanf(r, "%s %d %d\n", &key, &value, &nanos); err != nil {
+		return
+	}
+
+	return key, value, time.Unix(0, nanos), nil
+}
+
+func TestSaveLoad(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 30*time.Millisecond)
+	c.Set("b", 2, 40*time.Millisecond)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.Save(&buf, lineCodec{}))
+	require.Equal(t, 2, strings.Count(buf.String(), "\n"))
+
+	d := mcache.New[string, int]()
+
+	require.NoError(t, d.Load(&buf, lineCodec{}))
+	require.Equal(t, []string{"a", "b"}, d.Keys())
+	require.Equal(t, []int{1, 2}, d.Values())
+
+	require.Error(t, d.Load(strings.NewReader("a b c\n"), lineCodec{}))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,71 @@
+package mcache
+
+import (
+	"math"
+	"time"
+)
+
+// never is the expiration time of pinned items held past their TTL.
+// It is the latest time representable in nanoseconds, so it survives snapshots, append logs, and JSON.
+var never = time.Unix(0, math.MaxInt64)
+
+// Pin protects the key from eviction by Evict and by the memory budget. If expire is false, the key does not
+// expire while pinned either, its expiration is postponed once it is due. Pins belong to keys rather than values,
+// so the key stays pinned when its value is replaced, or stored after deletion, until Unpin is called.
+func (c *Cache[K, V]) Pin(key K, expire bool) {
+	c.m.Lock()
+
+	if c.pins == nil {
+		c.pins = make(map[K]bool)
+	}
+
+	c.pins[key] = expire
+
+	c.m.Unlock()
+}
+
+// Unpin removes the pin of the key, reporting whether the key was pinned.
+// If the key was held past its TTL, it expires right away.
+func (c *Cache[K, V]) Unpin(key K) bool {
+	c.m.Lock()
+	defer c.m.Unlock()
+
+	expire, ok := c.pins[key]
+	if !ok {
+		return false
+	}
+
+	delete(c.pins, key)
+
+	if v, found := c.cache[key]; found && !expire && v.Ptr.Expires.Equal(never) {
+		c.reschedule(v.Ptr, c.now())
+	}
+
+	return true
+}
+
+// Pinned reports whether the key is pinned.
+func (c *Cache[K, V]) Pinned(key K) bool {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.pinned(key)
+}
+
+// pinned reports whether the key is pinned, must be called under the lock.
+func (c *Cache[K, V]) pinned(key K) bool {
+	_, ok := c.pins[key]
+
+	return ok
+}
+
+// held postpones expiration of the due item if it is pinned without expiration, reporting whether it was postponed.
+func (c *Cache[K, V]) held(i *item[K]) bool {
+	if expire, ok := c.pins[i.Key]; !ok || expire {
+		return false
+	}
+
+	c.reschedule(i, never)
+
+	return true
+}
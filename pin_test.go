@@ -0,0 +1,112 @@
+package mcache_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPin(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 10*time.Millisecond)
+	c.Set("b", 2, 20*time.Millisecond)
+	c.Set("c", 3, 30*time.Millisecond)
+
+	c.Pin("a", true)
+	require.True(t, c.Pinned("a"))
+
+	// Pinned items are skipped by eviction
+	require.Equal(t, 1, c.Evict(1))
+	require.True(t, c.Has("a"))
+	require.False(t, c.Has("b"))
+
+	require.Equal(t, 1, c.Evict(5))
+	require.Equal(t, []string{"a"}, c.Keys())
+
+	// Pinned items expire unless pinned without expiration
+	c.Pin("d", false)
+	c.Set("d", 4, 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return !c.Has("a")
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	require.True(t, c.Has("d"))
+
+	// The pin survives replacement
+	c.Set("d", 5, 10*time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+
+	value, ok := c.Get("d")
+	require.True(t, ok)
+	require.Equal(t, 5, value)
+
+	require.True(t, c.Unpin("d"))
+	require.False(t, c.Unpin("d"))
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	c.Unpin("a")
+}
+
+func TestPinMemoryBudget(t *testing.T) {
+	c := mcache.New[string, string](mcache.WithMemoryBudget[string, string](1200, nil))
+
+	c.Set("config", strings.Repeat("x", 500), 10*time.Millisecond)
+	c.Pin("config", true)
+
+	c.Set("a", strings.Repeat("a", 300), 20*time.Millisecond)
+	c.Set("b", strings.Repeat("b", 300), 20*time.Millisecond)
+
+	require.True(t, c.Has("config"))
+	require.False(t, c.Has("a"))
+	require.True(t, c.Has("b"))
+
+	c.Clear()
+}
+
+func TestPinSnapshot(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.Pin("held", false)
+	c.Set("held", 1, time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		ttl, ok := c.TTL("held")
+
+		return ok && ttl > time.Hour
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.WriteSnapshot(&buf))
+
+	restored := mcache.New[string, int]()
+	require.NoError(t, restored.ReadSnapshot(&buf))
+
+	value, ok := restored.Get("held")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	data, err := json.Marshal(c)
+	require.NoError(t, err)
+
+	restored = mcache.New[string, int]()
+	require.NoError(t, json.Unmarshal(data, restored))
+	require.True(t, restored.Has("held"))
+
+	require.True(t, c.Unpin("held"))
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 5*time.Millisecond)
+}
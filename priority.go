@@ -0,0 +1,68 @@
+package mcache
+
+import (
+	"sort"
+	"time"
+)
+
+// Priority defines the order items are evicted in by Evict and the memory budget.
+// Items of lower priority are evicted first, regardless of their expiration time.
+type Priority int8
+
+const (
+	// PriorityLow items are cheap to recompute, and are evicted before all others.
+	PriorityLow Priority = -1
+	// PriorityNormal is the priority of items stored without explicit one.
+	PriorityNormal Priority = 0
+	// PriorityHigh items are expensive to rebuild, and are evicted last.
+	PriorityHigh Priority = 1
+)
+
+// SetWithPriority works as Set, also setting the eviction priority of the item.
+// Any Priority value can be used, the named ones are only common choices.
+// The priority is kept when the value is updated in place, e.g. with Update, and is reset by Set.
+func (c *Cache[K, V]) SetWithPriority(key K, value V, ttl time.Duration, p Priority) {
+	c.m.Lock()
+
+	c.set(key, value, c.now().Add(ttl), c.opts.precision, false)
+
+	if v, ok := c.cache[key]; ok && p != PriorityNormal {
+		v.Ptr.Priority = p
+		c.ranked = true
+	}
+
+	c.unlock()
+}
+
+// victims calls fn with items in the order of eviction until it returns false: by priority, then by expiration time.
+// Pinned items are skipped. The function may unlink the item it is called with.
+func (c *Cache[K, V]) victims(fn func(*item[K]) bool) {
+	var priorities []Priority
+
+	if c.ranked {
+		seen := make(map[Priority]struct{})
+
+		for i := c.head; i != nil; i = i.Next {
+			if _, ok := seen[i.Priority]; !ok {
+				seen[i.Priority] = struct{}{}
+				priorities = append(priorities, i.Priority)
+			}
+		}
+
+		sort.Slice(priorities, func(i, j int) bool {
+			return priorities[i] < priorities[j]
+		})
+	}
+
+	for n := 0; n == 0 || n < len(priorities); n++ {
+		for i := c.head; i != nil; {
+			next := i.Next
+
+			if (len(priorities) == 0 || i.Priority == priorities[n]) && !c.pinned(i.Key) && !fn(i) {
+				return
+			}
+
+			i = next
+		}
+	}
+}
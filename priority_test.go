@@ -0,0 +1,28 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriority(t *testing.T) {
+	c := mcache.New[string, int]()
+
+	c.SetWithPriority("aggregate", 1, 10*time.Millisecond, mcache.PriorityHigh)
+	c.SetWithPriority("cheap", 2, 30*time.Millisecond, mcache.PriorityLow)
+	c.Set("normal", 3, 20*time.Millisecond)
+	c.SetWithPriority("cheaper", 4, 40*time.Millisecond, mcache.PriorityLow-1)
+
+	require.Equal(t, 2, c.Evict(2))
+	require.ElementsMatch(t, []string{"aggregate", "normal"}, c.Keys())
+
+	// The priority is kept by in-place updates
+	require.True(t, c.Update("aggregate", 5))
+	require.Equal(t, 1, c.Evict(1))
+	require.Equal(t, []string{"aggregate"}, c.Keys())
+
+	c.Clear()
+}
@@ -0,0 +1,71 @@
+package mcache
+
+import (
+	"sync"
+	"time"
+)
+
+// readEntry is an immutable copy of a stored item, published to the read index
+type readEntry[V any] struct {
+	value   V
+	expires time.Time
+	info    *entryInfo
+}
+
+// getIndexed works as get, but looks the key up in the read index without locking the cache.
+// Items past their expiration time are looked up under the lock, as they may be kept for the grace period.
+func (c *Cache[K, V]) getIndexed(key K) (V, bool) {
+	e, ok := c.reads.Load().Load(key)
+	if !ok || c.disabled.Load() {
+		var zero V
+
+		return zero, false
+	}
+
+	entry := e.(*readEntry[V])
+
+	if !c.now().Before(entry.expires) {
+		return c.getLocked(key)
+	}
+
+	if entry.info != nil {
+		entry.info.access(c.now())
+	}
+
+	return entry.value, true
+}
+
+// index publishes the current state of the key to the read index, must be called under the write lock.
+func (c *Cache[K, V]) index(key K) {
+	reads := c.reads.Load()
+	if reads == nil {
+		return
+	}
+
+	v, ok := c.cache[key]
+	if !ok {
+		reads.Delete(key)
+
+		return
+	}
+
+	reads.Store(key, &readEntry[V]{
+		value:   v.Value,
+		expires: v.Ptr.Expires,
+		info:    v.Ptr.Info,
+	})
+}
+
+// unindex removes the key from the read index, must be called under the write lock.
+func (c *Cache[K, V]) unindex(key K) {
+	if reads := c.reads.Load(); reads != nil {
+		reads.Delete(key)
+	}
+}
+
+// resetIndex empties the read index, must be called under the write lock.
+func (c *Cache[K, V]) resetIndex() {
+	if c.opts.lockFreeReads {
+		c.reads.Store(new(sync.Map))
+	}
+}
@@ -0,0 +1,161 @@
+package mcache_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockFreeReads(t *testing.T) {
+	c := mcache.New[int, string](mcache.WithLockFreeReads(), mcache.WithEntryInfo())
+
+	c.Set(1, "one", 50*time.Millisecond)
+	c.Set(2, "two", 50*time.Millisecond)
+	c.SetMany(map[int]string{3: "three", 4: "four"}, 50*time.Millisecond)
+
+	for key, expected := range map[int]string{1: "one", 2: "two", 3: "three", 4: "four"} {
+		value, ok := c.Get(key)
+		require.True(t, ok)
+		require.Equal(t, expected, value)
+	}
+
+	require.True(t, c.Update(1, "uno"))
+
+	old, ok := c.Swap(2, "dos")
+	require.True(t, ok)
+	require.Equal(t, "two", old)
+
+	require.True(t, c.Rekey(3, 30))
+	require.True(t, c.Delete(4))
+
+	value, ok := c.Get(1)
+	require.True(t, ok)
+	require.Equal(t, "uno", value)
+
+	value, ok = c.Get(2)
+	require.True(t, ok)
+	require.Equal(t, "dos", value)
+
+	_, ok = c.Get(3)
+	require.False(t, ok)
+
+	value, ok = c.Get(30)
+	require.True(t, ok)
+	require.Equal(t, "three", value)
+
+	_, ok = c.Get(4)
+	require.False(t, ok)
+
+	info, ok := c.GetEntryInfo(1)
+	require.True(t, ok)
+	require.Equal(t, uint64(2), info.Hits)
+
+	c.Disable()
+
+	_, ok = c.Get(1)
+	require.False(t, ok)
+
+	c.Enable()
+
+	c.Clear()
+
+	_, ok = c.Get(1)
+	require.False(t, ok)
+
+	c.Set(5, "five", 20*time.Millisecond)
+
+	f := c.Filter(func(int, string) bool { return true })
+
+	value, ok = f.Get(5)
+	require.True(t, ok)
+	require.Equal(t, "five", value)
+
+	assert.Eventually(t, func() bool {
+		_, ok := c.Get(5)
+
+		return !ok && 0 == c.Len()+f.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestLockFreeReadsGracePeriod(t *testing.T) {
+	c := mcache.New[int, int](mcache.WithLockFreeReads(), mcache.WithGracePeriod(30*time.Millisecond))
+
+	c.Set(1, 1, 10*time.Millisecond)
+
+	_, ok := c.Get(1)
+	require.True(t, ok)
+
+	require.True(t, c.Refresh(1, 20*time.Millisecond))
+
+	time.Sleep(25 * time.Millisecond)
+
+	_, ok = c.Get(1)
+	require.False(t, ok)
+
+	_, ok, expired := c.GetStale(1)
+	require.True(t, ok)
+	require.True(t, expired)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestLockFreeReadsConcurrent(t *testing.T) {
+	c := mcache.New[int, int](mcache.WithLockFreeReads())
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 1000; i++ {
+				if w%2 == 0 {
+					c.Set(i%10, i, 20*time.Millisecond)
+				} else {
+					c.Get(i % 10)
+				}
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func BenchmarkCacheGetParallel(b *testing.B) {
+	for name, opts := range map[string][]mcache.Option{
+		"locked":    nil,
+		"lock-free": {mcache.WithLockFreeReads()},
+	} {
+		b.Run(name, func(b *testing.B) {
+			c := mcache.New[int, int](opts...)
+
+			for i := 0; i < 1000; i++ {
+				c.Set(i, i, 100*time.Millisecond)
+			}
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					c.Get(i % 1000)
+				}
+			})
+
+			b.StopTimer()
+			c.Clear()
+		})
+	}
+}
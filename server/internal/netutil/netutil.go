@@ -0,0 +1,117 @@
+// Package netutil implements connection handling shared by the protocol servers.
+package netutil
+
+import (
+	"errors"
+	"net"
+	"sync"
+)
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = errors.New("mcache: server closed")
+
+// Server accepts connections, serving each one in its own goroutine, and closes them all on Close.
+type Server struct {
+	m         sync.Mutex
+	listeners map[net.Listener]struct{}
+	conns     map[net.Conn]struct{}
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// Serve accepts connections on the listener, calling handle for each one in its own goroutine.
+// The connection is closed once handle returns. Returns ErrServerClosed after Close,
+// or the error from accepting a connection.
+func (s *Server) Serve(l net.Listener, handle func(net.Conn)) error {
+	if !s.track(l, nil) {
+		return ErrServerClosed
+	}
+
+	defer s.untrack(l, nil)
+
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			s.m.Lock()
+			closed := s.closed
+			s.m.Unlock()
+
+			if closed {
+				return ErrServerClosed
+			}
+
+			return err
+		}
+
+		if !s.track(nil, conn) {
+			conn.Close()
+
+			return ErrServerClosed
+		}
+
+		go func() {
+			defer s.untrack(nil, conn)
+			defer conn.Close()
+
+			handle(conn)
+		}()
+	}
+}
+
+// Close closes all listeners and connections, and waits for connection goroutines to finish.
+func (s *Server) Close() error {
+	s.m.Lock()
+
+	s.closed = true
+
+	for l := range s.listeners {
+		l.Close()
+	}
+
+	for conn := range s.conns {
+		conn.Close()
+	}
+
+	s.m.Unlock()
+
+	s.wg.Wait()
+
+	return nil
+}
+
+func (s *Server) track(l net.Listener, conn net.Conn) bool {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.closed {
+		return false
+	}
+
+	if s.listeners == nil {
+		s.listeners = make(map[net.Listener]struct{})
+		s.conns = make(map[net.Conn]struct{})
+	}
+
+	if l != nil {
+		s.listeners[l] = struct{}{}
+	}
+
+	if conn != nil {
+		s.conns[conn] = struct{}{}
+	}
+
+	s.wg.Add(1)
+
+	return true
+}
+
+func (s *Server) untrack(l net.Listener, conn net.Conn) {
+	s.m.Lock()
+
+	delete(s.listeners, l)
+	delete(s.conns, conn)
+
+	s.m.Unlock()
+
+	s.wg.Done()
+}
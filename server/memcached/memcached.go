@@ -0,0 +1,321 @@
+/*
+Package memcached serves an mcache over the memcached text protocol.
+
+Supported commands are get, gets, set, add, replace, delete, touch, version, and quit.
+Item flags are not stored, values are always returned with zero flags.
+Expiration time follows memcached rules: zero means the item never expires,
+values up to 30 days are relative, and larger values are Unix timestamps.
+*/
+package memcached
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/server/internal/netutil"
+)
+
+const (
+	maxKeyLength = 250
+	maxValueSize = 1 << 20
+	maxLineSize  = 4096
+
+	// Expiration times larger than this are Unix timestamps
+	maxRelativeExpiration = 30 * 24 * 60 * 60
+
+	// TTL of items stored without expiration
+	noExpiration = 100 * 365 * 24 * time.Hour
+)
+
+// Server serves the cache over the memcached text protocol.
+type Server struct {
+	cache *mcache.Cache[string, []byte]
+	srv   netutil.Server
+}
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = netutil.ErrServerClosed
+
+// errClient is a malformed request, it is reported to the client without closing the connection
+type errClient string
+
+func (e errClient) Error() string { return string(e) }
+
+// New creates a server backed by the cache.
+func New(c *mcache.Cache[string, []byte]) *Server {
+	return &Server{cache: c}
+}
+
+// Serve accepts connections on the listener, serving each one in its own goroutine.
+// It returns ErrServerClosed after Close, or the error from accepting a connection.
+func (s *Server) Serve(l net.Listener) error {
+	return s.srv.Serve(l, func(conn net.Conn) { s.ServeConn(conn) })
+}
+
+// ServeConn serves a single connection until the client quits or the connection is closed.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+
+	r := bufio.NewReaderSize(conn, maxLineSize)
+	w := bufio.NewWriter(conn)
+
+	for {
+		line, err := r.ReadSlice('\n')
+		if err != nil {
+			if errors.Is(err, bufio.ErrBufferFull) {
+				w.WriteString("CLIENT_ERROR line too long\r\n")
+				w.Flush()
+			}
+
+			return
+		}
+
+		quit, err := s.handle(strings.Fields(string(line)), r, w)
+		if err != nil {
+			var clientErr errClient
+
+			if !errors.As(err, &clientErr) {
+				return
+			}
+
+			fmt.Fprintf(w, "CLIENT_ERROR %s\r\n", clientErr)
+		}
+
+		if quit || w.Flush() != nil {
+			return
+		}
+	}
+}
+
+// Close closes all listeners and connections, and waits for connection goroutines to finish.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// handle executes a single command, returning true if the connection should be closed.
+func (s *Server) handle(args []string, r *bufio.Reader, w *bufio.Writer) (bool, error) {
+	if len(args) == 0 {
+		w.WriteString("ERROR\r\n")
+
+		return false, nil
+	}
+
+	switch args[0] {
+	case "get", "gets":
+		return false, s.get(args[1:], args[0] == "gets", w)
+	case "set", "add", "replace":
+		return false, s.store(args[0], args[1:], r, w)
+	case "delete":
+		return false, s.delete(args[1:], w)
+	case "touch":
+		return false, s.touch(args[1:], w)
+	case "version":
+		w.WriteString("VERSION mcache\r\n")
+
+		return false, nil
+	case "quit":
+		return true, nil
+	default:
+		w.WriteString("ERROR\r\n")
+
+		return false, nil
+	}
+}
+
+// get handles get and gets commands: <command> <key>*. gets also returns entry versions as cas unique values.
+func (s *Server) get(keys []string, cas bool, w *bufio.Writer) error {
+	if len(keys) == 0 {
+		return errClient("key expected")
+	}
+
+	if cas {
+		// Entry versions serve as cas unique values
+		for _, key := range keys {
+			if value, version, ok := s.cache.GetVersioned(key); ok {
+				fmt.Fprintf(w, "VALUE %s 0 %d %d\r\n", key, len(value), version)
+				w.Write(value)
+				w.WriteString("\r\n")
+			}
+		}
+
+		w.WriteString("END\r\n")
+
+		return nil
+	}
+
+	values, found := s.cache.MGet(keys...)
+
+	for i, key := range keys {
+		if found[i] {
+			fmt.Fprintf(w, "VALUE %s 0 %d\r\n", key, len(values[i]))
+			w.Write(values[i])
+			w.WriteString("\r\n")
+		}
+	}
+
+	w.WriteString("END\r\n")
+
+	return nil
+}
+
+// store handles set, add, and replace commands: <command> <key> <flags> <exptime> <bytes> [noreply]
+func (s *Server) store(command string, args []string, r *bufio.Reader, w *bufio.Writer) error {
+	if len(args) != 4 && len(args) != 5 {
+		return errClient("bad command line format")
+	}
+
+	size, err := strconv.Atoi(args[3])
+	if err != nil || size < 0 || size > maxValueSize {
+		return errClient("bad data chunk")
+	}
+
+	value := make([]byte, size+2)
+
+	if _, err = io.ReadFull(r, value); err != nil {
+		return err
+	}
+
+	if value[size] != '\r' || value[size+1] != '\n' {
+		return errClient("bad data chunk")
+	}
+
+	value = value[:size]
+	key := args[0]
+
+	if err = checkKey(key); err != nil {
+		return err
+	}
+
+	if _, err = strconv.ParseUint(args[1], 10, 32); err != nil {
+		return errClient("bad command line format")
+	}
+
+	ttl, err := parseExpiration(args[2])
+	if err != nil {
+		return err
+	}
+
+	var stored bool
+
+	switch {
+	case ttl < 0:
+		// The item has already expired, storing it only removes the old value
+		stored = command == "set" || (command == "add") != s.cache.Has(key)
+
+		if stored {
+			s.cache.Delete(key)
+		}
+	case command == "add":
+		stored = s.cache.SetIfAbsent(key, value, ttl)
+	case command == "replace":
+		_, stored = s.cache.SwapWithTTL(key, value, ttl)
+	default:
+		s.cache.Set(key, value, ttl)
+
+		stored = true
+	}
+
+	if len(args) == 5 && args[4] == "noreply" {
+		return nil
+	}
+
+	if stored {
+		w.WriteString("STORED\r\n")
+	} else {
+		w.WriteString("NOT_STORED\r\n")
+	}
+
+	return nil
+}
+
+// delete handles delete <key> [noreply]
+func (s *Server) delete(args []string, w *bufio.Writer) error {
+	if len(args) != 1 && len(args) != 2 {
+		return errClient("bad command line format")
+	}
+
+	ok := s.cache.Delete(args[0])
+
+	if len(args) == 2 && args[1] == "noreply" {
+		return nil
+	}
+
+	if ok {
+		w.WriteString("DELETED\r\n")
+	} else {
+		w.WriteString("NOT_FOUND\r\n")
+	}
+
+	return nil
+}
+
+// touch handles touch <key> <exptime> [noreply]
+func (s *Server) touch(args []string, w *bufio.Writer) error {
+	if len(args) != 2 && len(args) != 3 {
+		return errClient("bad command line format")
+	}
+
+	ttl, err := parseExpiration(args[1])
+	if err != nil {
+		return err
+	}
+
+	var ok bool
+
+	if ttl < 0 {
+		ok = s.cache.Delete(args[0])
+	} else {
+		ok = s.cache.Refresh(args[0], ttl)
+	}
+
+	if len(args) == 3 && args[2] == "noreply" {
+		return nil
+	}
+
+	if ok {
+		w.WriteString("TOUCHED\r\n")
+	} else {
+		w.WriteString("NOT_FOUND\r\n")
+	}
+
+	return nil
+}
+
+func checkKey(key string) error {
+	if len(key) > maxKeyLength {
+		return errClient("key too long")
+	}
+
+	return nil
+}
+
+// parseExpiration converts memcached expiration time to TTL, negative TTL means the item has already expired.
+func parseExpiration(s string) (time.Duration, error) {
+	exp, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, errClient("bad command line format")
+	}
+
+	switch {
+	case exp == 0:
+		return noExpiration, nil
+	case exp < 0:
+		return -1, nil
+	case exp <= maxRelativeExpiration:
+		return time.Duration(exp) * time.Second, nil
+	}
+
+	ttl := time.Until(time.Unix(exp, 0))
+	if ttl <= 0 {
+		return -1, nil
+	}
+
+	return ttl, nil
+}
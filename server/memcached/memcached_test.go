@@ -0,0 +1,100 @@
+package memcached_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/server/memcached"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestServer(t *testing.T) {
+	c := mcache.New[string, []byte]()
+	s := memcached.New(c)
+
+	client, conn := net.Pipe()
+
+	go s.ServeConn(conn)
+
+	r := bufio.NewReader(client)
+
+	for _, step := range []struct{ request, response string }{
+		{"set a 0 1 5\r\nhello\r\n", "STORED\r\n"},
+		{"get a b\r\n", "VALUE a 0 5\r\nhello\r\nEND\r\n"},
+		{"add a 0 1 1\r\nx\r\n", "NOT_STORED\r\n"},
+		{"replace b 0 1 1\r\nx\r\n", "NOT_STORED\r\n"},
+		{"replace a 0 1 3\r\nbye\r\n", "STORED\r\n"},
+		{"gets a\r\n", "VALUE a 0 3 2\r\nbye\r\nEND\r\n"},
+		{"get a\r\n", "VALUE a 0 3\r\nbye\r\nEND\r\n"},
+		{"touch a 1\r\n", "TOUCHED\r\n"},
+		{"touch b 1\r\n", "NOT_FOUND\r\n"},
+		{"delete a\r\n", "DELETED\r\n"},
+		{"delete a\r\n", "NOT_FOUND\r\n"},
+		{"set b 0 1 1 noreply\r\nx\r\nget b\r\n", "VALUE b 0 1\r\nx\r\nEND\r\n"},
+		{"set b 0 -1 1\r\nx\r\n", "STORED\r\n"},
+		{"get b\r\n", "END\r\n"},
+		{"set b 0 1 1\r\nxyz\r\n", "CLIENT_ERROR bad data chunk\r\nERROR\r\n"},
+		{"get\r\n", "CLIENT_ERROR key expected\r\n"},
+		{"flush_all\r\n", "ERROR\r\n"},
+		{"version\r\n", "VERSION mcache\r\n"},
+	} {
+		go client.Write([]byte(step.request))
+
+		response := make([]byte, len(step.response))
+
+		_, err := io.ReadFull(r, response)
+		require.NoError(t, err, step.request)
+		require.Equal(t, step.response, string(response), step.request)
+	}
+
+	go client.Write([]byte("quit\r\n"))
+
+	_, err := r.ReadByte()
+	require.Error(t, err)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 1100*time.Millisecond, 50*time.Millisecond)
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	c := mcache.New[string, []byte]()
+	s := memcached.New(c)
+
+	served := make(chan error)
+
+	go func() { served <- s.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("set k 0 1 1\r\nv\r\n"))
+	require.NoError(t, err)
+
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "STORED\r\n", line)
+
+	require.NoError(t, s.Close())
+	require.ErrorIs(t, <-served, memcached.ErrServerClosed)
+	require.ErrorIs(t, s.Serve(l), memcached.ErrServerClosed)
+
+	conn.Close()
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 1100*time.Millisecond, 50*time.Millisecond)
+}
@@ -0,0 +1,390 @@
+/*
+Package resp serves an mcache over the Redis serialization protocol (RESP2),
+so redis-cli and Redis client libraries can be used with it.
+
+Supported commands are GET, SET (with EX, PX, NX, and XX options), DEL, EXPIRE, TTL, KEYS, PING, COMMAND, and QUIT.
+Keys set without expiration are stored with a TTL of a hundred years, which TTL reports as -1.
+*/
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/server/internal/netutil"
+)
+
+const (
+	maxBulkSize  = 16 << 20
+	maxArraySize = 1 << 20
+	maxLineSize  = 64 << 10
+
+	// TTL of items stored without expiration
+	noExpiration = 100 * 365 * 24 * time.Hour
+
+	// The longest accepted TTL, so that expiration time does not overflow
+	maxTTL = noExpiration
+)
+
+// Server serves the cache over RESP.
+type Server struct {
+	cache *mcache.Cache[string, []byte]
+	srv   netutil.Server
+}
+
+// ErrServerClosed is returned by Serve after Close.
+var ErrServerClosed = netutil.ErrServerClosed
+
+// errProtocol is a malformed request, it is reported to the client before closing the connection
+type errProtocol string
+
+func (e errProtocol) Error() string { return string(e) }
+
+// New creates a server backed by the cache.
+func New(c *mcache.Cache[string, []byte]) *Server {
+	return &Server{cache: c}
+}
+
+// Serve accepts connections on the listener, serving each one in its own goroutine.
+// It returns ErrServerClosed after Close, or the error from accepting a connection.
+func (s *Server) Serve(l net.Listener) error {
+	return s.srv.Serve(l, func(conn net.Conn) { s.ServeConn(conn) })
+}
+
+// Close closes all listeners and connections, and waits for connection goroutines to finish.
+func (s *Server) Close() error {
+	return s.srv.Close()
+}
+
+// ServeConn serves a single connection until the client quits or the connection is closed.
+func (s *Server) ServeConn(conn io.ReadWriteCloser) {
+	defer conn.Close()
+
+	r := bufio.NewReaderSize(conn, maxLineSize)
+	w := bufio.NewWriter(conn)
+
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			var protoErr errProtocol
+
+			if errors.As(err, &protoErr) {
+				writeError(w, "ERR Protocol error: "+string(protoErr))
+				w.Flush()
+			}
+
+			return
+		}
+
+		if len(args) == 0 {
+			continue
+		}
+
+		quit := s.handle(args, w)
+
+		// Flush once the pipelined commands are all handled
+		if r.Buffered() == 0 || quit {
+			if w.Flush() != nil || quit {
+				return
+			}
+		}
+	}
+}
+
+// handle executes a single command, returning true if the connection should be closed.
+func (s *Server) handle(args []string, w *bufio.Writer) bool {
+	switch cmd := strings.ToUpper(args[0]); cmd {
+	case "PING":
+		if len(args) > 1 {
+			writeBulk(w, []byte(args[1]))
+		} else {
+			w.WriteString("+PONG\r\n")
+		}
+	case "GET":
+		if !arity(w, args, 2, 2) {
+			break
+		}
+
+		if value, ok := s.cache.Get(args[1]); ok {
+			writeBulk(w, value)
+		} else {
+			writeNull(w)
+		}
+	case "SET":
+		s.set(args, w)
+	case "DEL":
+		if !arity(w, args, 2, -1) {
+			break
+		}
+
+		writeInt(w, int64(s.cache.DeleteMany(args[1:]...)))
+	case "EXPIRE":
+		s.expire(args, w)
+	case "TTL":
+		if !arity(w, args, 2, 2) {
+			break
+		}
+
+		ttl, ok := s.cache.TTL(args[1])
+
+		switch {
+		case !ok:
+			writeInt(w, -2)
+		case ttl > noExpiration/2:
+			writeInt(w, -1)
+		default:
+			writeInt(w, int64((ttl+time.Second/2)/time.Second))
+		}
+	case "KEYS":
+		if !arity(w, args, 2, 2) {
+			break
+		}
+
+		s.keys(args[1], w)
+	case "COMMAND":
+		w.WriteString("*0\r\n")
+	case "QUIT":
+		w.WriteString("+OK\r\n")
+
+		return true
+	default:
+		writeError(w, fmt.Sprintf("ERR unknown command '%s'", args[0]))
+	}
+
+	return false
+}
+
+// set handles SET key value [EX seconds | PX milliseconds] [NX | XX]
+func (s *Server) set(args []string, w *bufio.Writer) {
+	if !arity(w, args, 3, -1) {
+		return
+	}
+
+	key, value := args[1], []byte(args[2])
+	ttl := noExpiration
+
+	var nx, xx bool
+
+	for i := 3; i < len(args); i++ {
+		switch opt := strings.ToUpper(args[i]); opt {
+		case "NX":
+			nx = true
+		case "XX":
+			xx = true
+		case "EX", "PX":
+			if i+1 == len(args) {
+				writeError(w, "ERR syntax error")
+
+				return
+			}
+
+			i++
+
+			unit := time.Second
+			if opt == "PX" {
+				unit = time.Millisecond
+			}
+
+			n, err := strconv.ParseInt(args[i], 10, 64)
+			if err != nil || n <= 0 || n > int64(maxTTL/unit) {
+				writeError(w, "ERR invalid expire time in 'set' command")
+
+				return
+			}
+
+			ttl = time.Duration(n) * unit
+		default:
+			writeError(w, "ERR syntax error")
+
+			return
+		}
+	}
+
+	if nx && xx {
+		writeError(w, "ERR syntax error")
+
+		return
+	}
+
+	stored := true
+
+	switch {
+	case nx:
+		stored = s.cache.SetIfAbsent(key, value, ttl)
+	case xx:
+		_, stored = s.cache.SwapWithTTL(key, value, ttl)
+	default:
+		s.cache.Set(key, value, ttl)
+	}
+
+	if stored {
+		w.WriteString("+OK\r\n")
+	} else {
+		writeNull(w)
+	}
+}
+
+// expire handles EXPIRE key seconds
+func (s *Server) expire(args []string, w *bufio.Writer) {
+	if !arity(w, args, 3, 3) {
+		return
+	}
+
+	seconds, err := strconv.ParseInt(args[2], 10, 64)
+	if err != nil {
+		writeError(w, "ERR value is not an integer or out of range")
+
+		return
+	}
+
+	if seconds > int64(maxTTL/time.Second) {
+		writeError(w, "ERR invalid expire time in 'expire' command")
+
+		return
+	}
+
+	var ok bool
+
+	if seconds <= 0 {
+		ok = s.cache.Delete(args[1])
+	} else {
+		ok = s.cache.Refresh(args[1], time.Duration(seconds)*time.Second)
+	}
+
+	if ok {
+		writeInt(w, 1)
+	} else {
+		writeInt(w, 0)
+	}
+}
+
+// keys handles KEYS pattern, the pattern syntax is that of path.Match, keys are returned sorted
+func (s *Server) keys(pattern string, w *bufio.Writer) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		writeError(w, "ERR invalid pattern")
+
+		return
+	}
+
+	var keys []string
+
+	for _, key := range s.cache.Keys() {
+		if ok, _ := path.Match(pattern, key); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+
+	fmt.Fprintf(w, "*%d\r\n", len(keys))
+
+	for _, key := range keys {
+		writeBulk(w, []byte(key))
+	}
+}
+
+// arity checks the number of arguments, including the command name, reporting the error to the client.
+// Negative max means no upper limit.
+func arity(w *bufio.Writer, args []string, min, max int) bool {
+	if len(args) < min || (max >= 0 && len(args) > max) {
+		writeError(w, fmt.Sprintf("ERR wrong number of arguments for '%s' command", strings.ToLower(args[0])))
+
+		return false
+	}
+
+	return true
+}
+
+// readCommand reads a command either as an array of bulk strings, or as an inline command.
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(line) == 0 || line[0] != '*' {
+		return strings.Fields(line), nil
+	}
+
+	n, err := strconv.Atoi(line[1:])
+	if err != nil || n < -1 || n > maxArraySize {
+		return nil, errProtocol("invalid multibulk length")
+	}
+
+	// Null array is an empty command
+	if n == -1 {
+		return nil, nil
+	}
+
+	args := make([]string, 0, n)
+
+	for i := 0; i < n; i++ {
+		if line, err = readLine(r); err != nil {
+			return nil, err
+		}
+
+		if len(line) == 0 || line[0] != '$' {
+			return nil, errProtocol(fmt.Sprintf("expected '$', got '%.1s'", line))
+		}
+
+		size, err := strconv.Atoi(line[1:])
+		if err != nil || size < 0 || size > maxBulkSize {
+			return nil, errProtocol("invalid bulk length")
+		}
+
+		data := make([]byte, size+2)
+
+		if _, err = io.ReadFull(r, data); err != nil {
+			return nil, err
+		}
+
+		if data[size] != '\r' || data[size+1] != '\n' {
+			return nil, errProtocol("invalid bulk string")
+		}
+
+		args = append(args, string(data[:size]))
+	}
+
+	return args, nil
+}
+
+// readLine reads a line without the line ending.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadSlice('\n')
+	if err != nil {
+		if errors.Is(err, bufio.ErrBufferFull) {
+			return "", errProtocol("too big inline request")
+		}
+
+		return "", err
+	}
+
+	return strings.TrimRight(string(line), "\r\n"), nil
+}
+
+func writeBulk(w *bufio.Writer, data []byte) {
+	fmt.Fprintf(w, "$%d\r\n", len(data))
+	w.Write(data)
+	w.WriteString("\r\n")
+}
+
+func writeNull(w *bufio.Writer) {
+	w.WriteString("$-1\r\n")
+}
+
+func writeInt(w *bufio.Writer, n int64) {
+	fmt.Fprintf(w, ":%d\r\n", n)
+}
+
+func writeError(w *bufio.Writer, msg string) {
+	w.WriteString("-" + msg + "\r\n")
+}
@@ -0,0 +1,125 @@
+package resp_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/server/resp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestServer(t *testing.T) {
+	c := mcache.New[string, []byte]()
+	s := resp.New(c)
+
+	client, conn := net.Pipe()
+
+	go s.ServeConn(conn)
+
+	r := bufio.NewReader(client)
+
+	for _, step := range []struct{ request, response string }{
+		{"PING\r\n", "+PONG\r\n"},
+		{"*3\r\n$3\r\nSET\r\n$1\r\na\r\n$5\r\nhello\r\n", "+OK\r\n"},
+		{"*2\r\n$3\r\nGET\r\n$1\r\na\r\n", "$5\r\nhello\r\n"},
+		{"GET b\r\n", "$-1\r\n"},
+		{"SET a x NX\r\n", "$-1\r\n"},
+		{"SET b x XX\r\n", "$-1\r\n"},
+		{"set b bee px 1000\r\n", "+OK\r\n"},
+		{"TTL a\r\n", ":-1\r\n"},
+		{"TTL b\r\n", ":1\r\n"},
+		{"TTL c\r\n", ":-2\r\n"},
+		{"SET b bye XX EX 5\r\n", "+OK\r\n"},
+		{"TTL b\r\n", ":5\r\n"},
+		{"EXPIRE a 1\r\n", ":1\r\n"},
+		{"EXPIRE c 1\r\n", ":0\r\n"},
+		{"KEYS *\r\n", "*2\r\n$1\r\na\r\n$1\r\nb\r\n"},
+		{"KEYS [\r\n", "-ERR invalid pattern\r\n"},
+		{"DEL a b c\r\n", ":2\r\n"},
+		{"SET a x EX 0\r\n", "-ERR invalid expire time in 'set' command\r\n"},
+		{"SET a x EX 9223372036854775807\r\n", "-ERR invalid expire time in 'set' command\r\n"},
+		{"SET a x PX 9223372036854775807\r\n", "-ERR invalid expire time in 'set' command\r\n"},
+		{"EXPIRE a 9223372036854775807\r\n", "-ERR invalid expire time in 'expire' command\r\n"},
+		{"*-1\r\nPING\r\n", "+PONG\r\n"},
+		{"SET a\r\n", "-ERR wrong number of arguments for 'set' command\r\n"},
+		{"FLUSHALL\r\n", "-ERR unknown command 'FLUSHALL'\r\n"},
+		{"COMMAND DOCS\r\n", "*0\r\n"},
+		{"SET c 1 PX 30\r\nGET c\r\n", "+OK\r\n$1\r\n1\r\n"},
+	} {
+		go client.Write([]byte(step.request))
+
+		response := make([]byte, len(step.response))
+
+		_, err := io.ReadFull(r, response)
+		require.NoError(t, err, step.request)
+		require.Equal(t, step.response, string(response), step.request)
+	}
+
+	go client.Write([]byte("*1\r\n+bad\r\n"))
+
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "-ERR Protocol error: expected '$', got '+'\r\n", line)
+
+	_, err = r.ReadByte()
+	require.Error(t, err)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestNegativeMultibulkLength(t *testing.T) {
+	s := resp.New(mcache.New[string, []byte]())
+
+	client, conn := net.Pipe()
+
+	go s.ServeConn(conn)
+
+	r := bufio.NewReader(client)
+
+	go client.Write([]byte("*-5\r\n"))
+
+	line, err := r.ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "-ERR Protocol error: invalid multibulk length\r\n", line)
+
+	_, err = r.ReadByte()
+	require.Error(t, err)
+}
+
+func TestServe(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	s := resp.New(mcache.New[string, []byte]())
+
+	served := make(chan error)
+
+	go func() { served <- s.Serve(l) }()
+
+	conn, err := net.Dial("tcp", l.Addr().String())
+	require.NoError(t, err)
+
+	_, err = conn.Write([]byte("PING\r\nQUIT\r\n"))
+	require.NoError(t, err)
+
+	data, err := io.ReadAll(conn)
+	require.NoError(t, err)
+	require.Equal(t, "+PONG\r\n+OK\r\n", string(data))
+
+	require.NoError(t, s.Close())
+	require.ErrorIs(t, <-served, resp.ErrServerClosed)
+
+	conn.Close()
+}
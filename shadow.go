@@ -0,0 +1,123 @@
+package mcache
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Shadow serves all operations from the primary cache, mirroring them to the shadow cache,
+// and records differences in hit rate and latency between the two.
+// It allows evaluating a different cache configuration on real traffic before switching to it.
+type Shadow[K comparable, V any] struct {
+	primary *Cache[K, V]
+	shadow  *Cache[K, V]
+	stats   shadowStats
+}
+
+// ShadowStats holds comparison of the primary and shadow caches.
+type ShadowStats struct {
+	Gets           uint64        // Number of Get calls
+	PrimaryHits    uint64        // Number of hits in the primary cache
+	ShadowHits     uint64        // Number of hits in the shadow cache
+	Divergences    uint64        // Number of Get calls where only one of the caches had the key
+	PrimaryLatency time.Duration // Total time spent in the primary cache
+	ShadowLatency  time.Duration // Total time spent in the shadow cache
+}
+
+type shadowStats struct {
+	gets           atomic.Uint64
+	primaryHits    atomic.Uint64
+	shadowHits     atomic.Uint64
+	divergences    atomic.Uint64
+	primaryLatency atomic.Int64
+	shadowLatency  atomic.Int64
+}
+
+// NewShadow creates a cache serving values from primary and mirroring all operations to shadow.
+func NewShadow[K comparable, V any](primary, shadow *Cache[K, V]) *Shadow[K, V] {
+	return &Shadow[K, V]{
+		primary: primary,
+		shadow:  shadow,
+	}
+}
+
+// Get returns value from the primary cache, recording whether the shadow cache has it too.
+func (s *Shadow[K, V]) Get(key K) (V, bool) {
+	start := time.Now()
+	value, ok := s.primary.Get(key)
+	s.stats.primaryLatency.Add(int64(time.Since(start)))
+
+	start = time.Now()
+	_, shadowOK := s.shadow.Get(key)
+	s.stats.shadowLatency.Add(int64(time.Since(start)))
+
+	s.stats.gets.Add(1)
+
+	if ok {
+		s.stats.primaryHits.Add(1)
+	}
+
+	if shadowOK {
+		s.stats.shadowHits.Add(1)
+	}
+
+	if ok != shadowOK {
+		s.stats.divergences.Add(1)
+	}
+
+	return value, ok
+}
+
+// Set sets the value in both caches.
+func (s *Shadow[K, V]) Set(key K, value V, ttl time.Duration) {
+	start := time.Now()
+	s.primary.Set(key, value, ttl)
+	s.stats.primaryLatency.Add(int64(time.Since(start)))
+
+	start = time.Now()
+	s.shadow.Set(key, value, ttl)
+	s.stats.shadowLatency.Add(int64(time.Since(start)))
+}
+
+// Delete deletes the key from both caches, returning result of the primary cache.
+func (s *Shadow[K, V]) Delete(key K) bool {
+	start := time.Now()
+	ok := s.primary.Delete(key)
+	s.stats.primaryLatency.Add(int64(time.Since(start)))
+
+	start = time.Now()
+	s.shadow.Delete(key)
+	s.stats.shadowLatency.Add(int64(time.Since(start)))
+
+	return ok
+}
+
+// Refresh sets new TTL for the key in both caches, returning result of the primary cache.
+func (s *Shadow[K, V]) Refresh(key K, ttl time.Duration) bool {
+	start := time.Now()
+	ok := s.primary.Refresh(key, ttl)
+	s.stats.primaryLatency.Add(int64(time.Since(start)))
+
+	start = time.Now()
+	s.shadow.Refresh(key, ttl)
+	s.stats.shadowLatency.Add(int64(time.Since(start)))
+
+	return ok
+}
+
+// Len returns number of items in the primary cache.
+func (s *Shadow[K, V]) Len() int {
+	return s.primary.Len()
+}
+
+// Stats returns comparison of the primary and shadow caches collected so far.
+func (s *Shadow[K, V]) Stats() ShadowStats {
+	return ShadowStats{
+		Gets:           s.stats.gets.Load(),
+		PrimaryHits:    s.stats.primaryHits.Load(),
+		ShadowHits:     s.stats.shadowHits.Load(),
+		Divergences:    s.stats.divergences.Load(),
+		PrimaryLatency: time.Duration(s.stats.primaryLatency.Load()),
+		ShadowLatency:  time.Duration(s.stats.shadowLatency.Load()),
+	}
+}
@@ -0,0 +1,36 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestShadow(t *testing.T) {
+	primary := mcache.New[string, int]()
+	shadow := mcache.New[string, int]()
+	s := mcache.NewShadow(primary, shadow)
+
+	s.Set("a", 1, 50*time.Millisecond)
+	shadow.Delete("a")
+
+	if v, ok := s.Get("a"); assert.True(t, ok) {
+		assert.Equal(t, 1, v)
+	}
+
+	_, ok := s.Get("b")
+	require.False(t, ok)
+
+	stats := s.Stats()
+	require.Equal(t, uint64(2), stats.Gets)
+	require.Equal(t, uint64(1), stats.PrimaryHits)
+	require.Equal(t, uint64(0), stats.ShadowHits)
+	require.Equal(t, uint64(1), stats.Divergences)
+
+	require.True(t, s.Refresh("a", 10*time.Millisecond))
+	require.True(t, s.Delete("a"))
+	require.Zero(t, s.Len())
+}
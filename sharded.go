@@ -0,0 +1,206 @@
+package mcache
+
+import (
+	"encoding/binary"
+	"hash/maphash"
+	"time"
+)
+
+// Sharded partitions keys across independent caches, each with its own lock and expiration timer,
+// so writers of different keys do not contend with each other.
+type Sharded[K comparable, V any] struct {
+	shards []*Cache[K, V]
+	seed   maphash.Seed
+}
+
+var _ Cacher[int, int] = (*Sharded[int, int])(nil)
+
+// NewSharded creates a cache split into the given number of shards, with options applied to every shard.
+// Options starting background work, such as WithSnapshot, must not be used, as every shard would do it on its own.
+func NewSharded[K comparable, V any](shards int, opts ...Option) *Sharded[K, V] {
+	if shards < 1 {
+		shards = 1
+	}
+
+	s := &Sharded[K, V]{
+		shards: make([]*Cache[K, V], shards),
+		seed:   maphash.MakeSeed(),
+	}
+
+	for i := range s.shards {
+		s.shards[i] = New[K, V](opts...)
+	}
+
+	return s
+}
+
+// Set adds or replaces a value with key and given TTL.
+func (s *Sharded[K, V]) Set(key K, value V, ttl time.Duration) {
+	s.shard(key).Set(key, value, ttl)
+}
+
+// SetUntil adds or replaces a value with key, expiring at the given time.
+func (s *Sharded[K, V]) SetUntil(key K, value V, expireAt time.Time) {
+	s.shard(key).SetUntil(key, value, expireAt)
+}
+
+// SetIfAbsent stores the value only if the key is not present, reporting whether the value was stored.
+func (s *Sharded[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	return s.shard(key).SetIfAbsent(key, value, ttl)
+}
+
+// GetOrSet returns the existing value for the key if present, otherwise stores and returns the given value.
+// The bool result is true if the value was loaded.
+func (s *Sharded[K, V]) GetOrSet(key K, value V, ttl time.Duration) (V, bool) {
+	return s.shard(key).GetOrSet(key, value, ttl)
+}
+
+// Get returns value from the cache.
+func (s *Sharded[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+// GetWithExpiry returns value from the cache along with its expiration time.
+func (s *Sharded[K, V]) GetWithExpiry(key K) (V, time.Time, bool) {
+	return s.shard(key).GetWithExpiry(key)
+}
+
+// TTL returns the remaining time to live of the key.
+func (s *Sharded[K, V]) TTL(key K) (time.Duration, bool) {
+	return s.shard(key).TTL(key)
+}
+
+// Has reports whether the key is present in the cache.
+func (s *Sharded[K, V]) Has(key K) bool {
+	return s.shard(key).Has(key)
+}
+
+// Swap replaces the value of the key keeping its TTL, returning the previous value.
+func (s *Sharded[K, V]) Swap(key K, value V) (V, bool) {
+	return s.shard(key).Swap(key, value)
+}
+
+// Update sets new value for the key keeping its TTL, reporting whether the key was present.
+func (s *Sharded[K, V]) Update(key K, value V) bool {
+	return s.shard(key).Update(key, value)
+}
+
+// Delete deletes the key from the cache, reporting whether it was present.
+func (s *Sharded[K, V]) Delete(key K) bool {
+	return s.shard(key).Delete(key)
+}
+
+// GetAndDelete returns the value and deletes the key from the cache.
+func (s *Sharded[K, V]) GetAndDelete(key K) (V, bool) {
+	return s.shard(key).GetAndDelete(key)
+}
+
+// Refresh sets new TTL for the key, reporting whether the key was present.
+func (s *Sharded[K, V]) Refresh(key K, ttl time.Duration) bool {
+	return s.shard(key).Refresh(key, ttl)
+}
+
+// Len returns number of items stored in all shards.
+func (s *Sharded[K, V]) Len() (n int) {
+	for _, c := range s.shards {
+		n += c.Len()
+	}
+
+	return
+}
+
+// Keys returns keys of all shards. Keys are ordered by expiration within each shard only.
+func (s *Sharded[K, V]) Keys() []K {
+	keys := make([]K, 0, s.Len())
+
+	for _, c := range s.shards {
+		keys = append(keys, c.Keys()...)
+	}
+
+	return keys
+}
+
+// Range iterates over key/value pairs of all shards, shard by shard, until fn returns false.
+func (s *Sharded[K, V]) Range(fn func(K, V) bool) {
+	for _, c := range s.shards {
+		stopped := false
+
+		c.Range(func(key K, value V) bool {
+			stopped = !fn(key, value)
+
+			return !stopped
+		})
+
+		if stopped {
+			return
+		}
+	}
+}
+
+// Sweep removes expired items from all shards, returning the number of removed items.
+func (s *Sharded[K, V]) Sweep() (n int) {
+	for _, c := range s.shards {
+		n += c.Sweep()
+	}
+
+	return
+}
+
+// Clear removes all items from all shards.
+func (s *Sharded[K, V]) Clear() {
+	for _, c := range s.shards {
+		c.Clear()
+	}
+}
+
+// Stats returns operation counters summed over all shards.
+func (s *Sharded[K, V]) Stats() (stats Stats) {
+	for _, c := range s.shards {
+		st := c.Stats()
+
+		stats.Hits += st.Hits
+		stats.Misses += st.Misses
+		stats.Sets += st.Sets
+		stats.Deletes += st.Deletes
+		stats.Expirations += st.Expirations
+		stats.Evictions += st.Evictions
+		stats.Dropped += st.Dropped
+		stats.Rejected += st.Rejected
+	}
+
+	return
+}
+
+// Close stops background work of all shards.
+func (s *Sharded[K, V]) Close() {
+	for _, c := range s.shards {
+		c.Close()
+	}
+}
+
+// shard returns the cache holding the key.
+func (s *Sharded[K, V]) shard(key K) *Cache[K, V] {
+	if len(s.shards) == 1 {
+		return s.shards[0]
+	}
+
+	return s.shards[hashKey(s.seed, key)%uint64(len(s.shards))]
+}
+
+// hashKey hashes keys of any comparable type, with fast paths for strings and integers.
+func hashKey[K comparable](seed maphash.Seed, key K) uint64 {
+	var buf [binary.MaxVarintLen64]byte
+
+	switch k := any(key).(type) {
+	case string:
+		return maphash.String(seed, k)
+	case int:
+		return maphash.Bytes(seed, buf[:binary.PutVarint(buf[:], int64(k))])
+	case int64:
+		return maphash.Bytes(seed, buf[:binary.PutVarint(buf[:], k)])
+	case uint64:
+		return maphash.Bytes(seed, buf[:binary.PutUvarint(buf[:], k)])
+	}
+
+	return hashComparable(seed, key)
+}
@@ -0,0 +1,145 @@
+package mcache_test
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSharded(t *testing.T) {
+	c := mcache.NewSharded[string, int](4)
+
+	var wg sync.WaitGroup
+
+	for w := 0; w < 4; w++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for i := 0; i < 25; i++ {
+				c.Set(fmt.Sprintf("%d-%d", w, i), i, 50*time.Millisecond)
+			}
+		}(w)
+	}
+
+	wg.Wait()
+
+	require.Equal(t, 100, c.Len())
+	require.Len(t, c.Keys(), 100)
+
+	value, ok := c.Get("2-7")
+	require.True(t, ok)
+	require.Equal(t, 7, value)
+
+	require.True(t, c.Has("3-24"))
+	require.False(t, c.SetIfAbsent("3-24", 0, time.Millisecond))
+	require.True(t, c.Update("3-24", 124))
+
+	old, ok := c.Swap("3-24", 224)
+	require.True(t, ok)
+	require.Equal(t, 124, old)
+
+	value, ok = c.GetAndDelete("3-24")
+	require.True(t, ok)
+	require.Equal(t, 224, value)
+
+	require.True(t, c.Delete("0-0"))
+	require.False(t, c.Delete("0-0"))
+
+	var keys []string
+
+	c.Range(func(key string, _ int) bool {
+		keys = append(keys, key)
+
+		return len(keys) < 10
+	})
+	require.Len(t, keys, 10)
+
+	stats := c.Stats()
+	require.Equal(t, uint64(100), stats.Sets)
+	require.Equal(t, uint64(1), stats.Hits)
+
+	ints := mcache.NewSharded[int, int](3)
+	for i := 0; i < 30; i++ {
+		ints.Set(i, i, 20*time.Millisecond)
+	}
+
+	intKeys := ints.Keys()
+	sort.Ints(intKeys)
+	require.Len(t, intKeys, 30)
+	require.Equal(t, 29, intKeys[29])
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+ints.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestShardedKeys(t *testing.T) {
+	// Equal keys must be found in the same shard
+	floats := mcache.NewSharded[float64, int](16)
+
+	negativeZero := math.Copysign(0, -1)
+
+	floats.Set(0, 1, 20*time.Millisecond)
+
+	value, ok := floats.Get(negativeZero)
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+
+	type point struct{ x, y int }
+
+	points := mcache.NewSharded[point, int](16)
+	anys := mcache.NewSharded[any, int](16)
+
+	for i := 0; i < 30; i++ {
+		points.Set(point{i, -i}, i, 20*time.Millisecond)
+		anys.Set(i, i, 20*time.Millisecond)
+	}
+
+	for i := 0; i < 30; i++ {
+		value, ok := points.Get(point{i, -i})
+		require.True(t, ok)
+		require.Equal(t, i, value)
+
+		value, ok = anys.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, value)
+	}
+
+	assert.Eventually(t, func() bool {
+		return 0 == floats.Len()+points.Len()+anys.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func BenchmarkShardedStructKeys(b *testing.B) {
+	type point struct{ x, y int }
+
+	c := mcache.NewSharded[point, int](16)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		c.Set(point{i, i}, i, 50*time.Millisecond)
+	}
+}
+
+func BenchmarkShardedSetParallel(b *testing.B) {
+	c := mcache.NewSharded[int, int](16)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			c.Set(i, i, 50*time.Millisecond)
+		}
+	})
+}
@@ -0,0 +1,185 @@
+package mcache
+
+import "unsafe"
+
+// Bytes the map spends per entry on top of the key and the value, assuming the average load factor
+const mapEntryOverhead = 8
+
+// EstimatedBytes returns approximate memory held by stored keys, values, and the internal structures
+// the cache keeps for them. Contents of string and byte slice keys and values are counted, other memory
+// referenced by keys and values is only counted with WithSizer. The map capacity left after deletions is not counted.
+func (c *Cache[K, V]) EstimatedBytes() int64 {
+	c.m.RLock()
+	defer c.m.RUnlock()
+
+	return c.size
+}
+
+// store puts the value to the map and the read index, accounting for its size, and assigns the next version.
+func (c *Cache[K, V]) store(key K, v valuePtr[K, V]) {
+	c.ver++
+	v.Ptr.Version = c.ver
+
+	if v.Ptr.Priority != PriorityNormal {
+		c.ranked = true
+	}
+
+	if old, ok := c.cache[key]; ok {
+		c.size -= c.entrySize(key, old.Value)
+		delete(c.costs, key) // The new value is estimated anew
+	}
+
+	c.cache[key] = v
+	c.size += c.entrySize(key, v.Value)
+
+	c.index(key)
+}
+
+// UpdateCost replaces the size of the key and value contents, as estimated by the sizer set with WithSizer
+// or WithMemoryBudget, with the given one. It is meant for values whose size changes after they are stored,
+// e.g. when they are populated lazily. The cost is kept until the value is replaced.
+// Items are evicted if the cache no longer fits the memory budget. Returns false if the key is not found.
+func (c *Cache[K, V]) UpdateCost(key K, cost int64) bool {
+	c.m.Lock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.Unlock()
+
+		return false
+	}
+
+	c.size -= c.entrySize(key, v.Value)
+
+	if c.costs == nil {
+		c.costs = make(map[K]int64)
+	}
+
+	c.costs[key] = cost
+	c.size += c.entrySize(key, v.Value)
+
+	c.unlock()
+
+	return true
+}
+
+// entrySize estimates memory held by the stored item.
+func (c *Cache[K, V]) entrySize(key K, value V) int64 {
+	var v valuePtr[K, V]
+
+	size := int64(unsafe.Sizeof(key)+unsafe.Sizeof(v)+unsafe.Sizeof(item[K]{})) + mapEntryOverhead
+
+	if c.opts.entryInfo {
+		size += int64(unsafe.Sizeof(entryInfo{}))
+	}
+
+	if cost, ok := c.costs[key]; ok {
+		return size + cost
+	}
+
+	if c.sizer != nil {
+		return size + int64(c.sizer(key, value))
+	}
+
+	return size + int64(contentSize(key)+contentSize(value))
+}
+
+// contentSize returns the length of string and byte slice contents.
+func contentSize[T any](v T) int {
+	switch v := any(v).(type) {
+	case string:
+		return len(v)
+	case []byte:
+		return cap(v)
+	case CompressedValue:
+		return cap(v.Data)
+	}
+
+	return 0
+}
+
+// unlock releases the write lock, first evicting the earliest expiring items while the estimated memory
+// exceeds the budget set with WithMemoryBudget. Eviction callback is called for them after unlocking.
+func (c *Cache[K, V]) unlock() {
+	var items []KeyValue[K, V]
+
+	if c.opts.budget > 0 && c.size > c.opts.budget {
+		items = c.trim()
+	}
+
+	logger := c.opts.logger
+
+	c.m.Unlock()
+
+	c.notify(items, Evicted, logger)
+}
+
+// trim evicts the earliest expiring items of the lowest priority until the estimated memory fits the budget,
+// pinned items are skipped.
+func (c *Cache[K, V]) trim() (items []KeyValue[K, V]) {
+	head := c.head
+	evicted := 0
+
+	c.victims(func(i *item[K]) bool {
+		if c.size <= c.opts.budget {
+			return false
+		}
+
+		if c.onEvict != nil {
+			items = append(items, KeyValue[K, V]{Key: i.Key, Value: c.cache[i.Key].Value})
+		}
+
+		c.publishRemoval(EventEvict, i.Key)
+
+		c.unlink(i.Key, i)
+
+		evicted++
+
+		return true
+	})
+
+	switch {
+	case c.head == nil:
+		c.stopTimer()
+	case c.head != head:
+		c.setTimer()
+	}
+
+	c.stats.evictions.Add(uint64(evicted))
+
+	if c.opts.logger != nil && evicted > 0 {
+		c.opts.logger.Debug("mcache: items evicted over memory budget", "count", evicted, "bytes", c.size)
+	}
+
+	return
+}
+
+// admit checks the value against the size limit set with WithMaxValueSize, returning the value to store
+// and false if the value is rejected.
+func (c *Cache[K, V]) admit(key K, value V) (V, bool) {
+	if c.opts.maxValueSize <= 0 {
+		return value, true
+	}
+
+	var size int
+
+	if c.valueSizer != nil {
+		size = c.valueSizer(value)
+	} else {
+		size = contentSize(value)
+	}
+
+	if size <= c.opts.maxValueSize {
+		return value, true
+	}
+
+	if c.oversized != nil {
+		if value, ok := c.oversized(key, value); ok {
+			return value, true
+		}
+	}
+
+	c.stats.rejected.Add(1)
+
+	return value, false
+}
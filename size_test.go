@@ -0,0 +1,183 @@
+package mcache_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEstimatedBytes(t *testing.T) {
+	c := mcache.New[string, string]()
+
+	require.Zero(t, c.EstimatedBytes())
+
+	c.Set("a", "1", 50*time.Millisecond)
+
+	entry := c.EstimatedBytes()
+	require.Positive(t, entry)
+
+	c.Set("b", "1234567890", 50*time.Millisecond)
+	require.Equal(t, 2*entry+9, c.EstimatedBytes())
+
+	c.Update("b", "1")
+	require.Equal(t, 2*entry, c.EstimatedBytes())
+
+	c.Set("a", "1", 50*time.Millisecond)
+	require.Equal(t, 2*entry, c.EstimatedBytes())
+
+	c.Delete("a")
+	require.Equal(t, entry, c.EstimatedBytes())
+
+	c.Clear()
+	require.Zero(t, c.EstimatedBytes())
+
+	c.Set("a", "1", 10*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len() && 0 == c.EstimatedBytes()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWithSizer(t *testing.T) {
+	c := mcache.New[int, []int](mcache.WithSizer(func(_ int, value []int) int {
+		return 8 * cap(value)
+	}))
+
+	c.Set(1, nil, 20*time.Millisecond)
+
+	entry := c.EstimatedBytes()
+
+	c.Set(2, make([]int, 10), 20*time.Millisecond)
+	require.Equal(t, 2*entry+80, c.EstimatedBytes())
+
+	require.Panics(t, func() {
+		mcache.New[int, int](mcache.WithSizer(func(int, string) int { return 0 }))
+	})
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len() && 0 == c.EstimatedBytes()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWithMemoryBudget(t *testing.T) {
+	var evicted []int
+
+	c := mcache.New[int, string](
+		mcache.WithMemoryBudget(4000, func(_ int, value string) int {
+			return len(value)
+		}),
+		mcache.WithEvictionCallback(func(key int, _ string, reason mcache.Reason) {
+			require.Equal(t, mcache.Evicted, reason)
+
+			evicted = append(evicted, key)
+		}),
+	)
+
+	for i := 1; i <= 4; i++ {
+		c.Set(i, strings.Repeat("x", 800), time.Duration(i)*10*time.Millisecond)
+	}
+
+	require.Equal(t, 4, c.Len())
+	require.Empty(t, evicted)
+
+	// The earliest expiring items make room for the new one
+	c.Set(5, strings.Repeat("x", 1200), 50*time.Millisecond)
+	require.Equal(t, []int{1, 2}, evicted)
+	require.Equal(t, []int{3, 4, 5}, c.Keys())
+	require.LessOrEqual(t, c.EstimatedBytes(), int64(4000))
+
+	// Growing value counts too
+	require.True(t, c.Update(5, strings.Repeat("x", 3000)))
+	require.Equal(t, []int{1, 2, 3, 4}, evicted)
+	require.Equal(t, uint64(4), c.Stats().Evictions)
+
+	// Too large to fit on its own
+	c.Set(6, strings.Repeat("x", 5000), 60*time.Millisecond)
+	require.Equal(t, []int{1, 2, 3, 4, 5, 6}, evicted)
+	require.Zero(t, c.Len())
+	require.Zero(t, c.EstimatedBytes())
+}
+
+func TestUpdateCost(t *testing.T) {
+	sizer := func(int, int) int { return 0 }
+
+	probe := mcache.New[int, int](mcache.WithSizer(sizer))
+	probe.Set(1, 1, time.Millisecond)
+
+	entry := probe.EstimatedBytes()
+
+	probe.Clear()
+
+	c := mcache.New[int, int](mcache.WithMemoryBudget(2*entry+100, sizer))
+
+	c.Set(1, 1, 20*time.Millisecond)
+	c.Set(2, 2, 30*time.Millisecond)
+
+	require.True(t, c.UpdateCost(2, 60))
+	require.Equal(t, 2*entry+60, c.EstimatedBytes())
+
+	// Going over the budget evicts the earliest expiring item
+	require.True(t, c.UpdateCost(1, 60))
+	require.False(t, c.Has(1))
+	require.Equal(t, entry+60, c.EstimatedBytes())
+
+	// The cost follows the key, and is dropped once the value is replaced
+	require.True(t, c.Rekey(2, 3))
+	require.Equal(t, entry+60, c.EstimatedBytes())
+
+	require.True(t, c.Update(3, 5))
+	require.Equal(t, entry, c.EstimatedBytes())
+
+	require.False(t, c.UpdateCost(4, 1))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len() && 0 == c.EstimatedBytes()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWithMaxValueSize(t *testing.T) {
+	c := mcache.New[string, []byte](mcache.WithMaxValueSize[[]byte](4, nil))
+
+	c.Set("a", []byte("abcd"), 20*time.Millisecond)
+	c.Set("b", []byte("abcde"), 20*time.Millisecond)
+
+	require.Equal(t, []string{"a"}, c.Keys())
+
+	// Rejected value does not leave the previous one
+	c.Set("a", []byte("abcde"), 20*time.Millisecond)
+	require.Zero(t, c.Len())
+
+	c.SetMany(map[string][]byte{"c": []byte("abc"), "d": []byte("abcdef")}, 20*time.Millisecond)
+	require.Equal(t, []string{"c"}, c.Keys())
+	require.Equal(t, uint64(3), c.Stats().Rejected)
+
+	truncated := mcache.New[string, string](
+		mcache.WithMaxValueSize(10, func(value string) int {
+			return len(value)
+		}),
+		mcache.WithOversizedValueHandler(func(key, value string) (string, bool) {
+			return value[:10], key != "reject"
+		}),
+	)
+
+	truncated.Set("a", "0123456789abc", 20*time.Millisecond)
+	truncated.Set("reject", "0123456789abc", 20*time.Millisecond)
+
+	value, ok := truncated.Get("a")
+	require.True(t, ok)
+	require.Equal(t, "0123456789", value)
+	require.False(t, truncated.Has("reject"))
+	require.Equal(t, uint64(1), truncated.Stats().Rejected)
+
+	require.Panics(t, func() {
+		mcache.New[int, int](mcache.WithMaxValueSize(1, func(string) int { return 0 }))
+	})
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+truncated.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
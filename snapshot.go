@@ -0,0 +1,418 @@
+package mcache
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding"
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+)
+
+// Binary snapshot layout, all integers are varint-encoded unless stated otherwise:
+//
+//	magic   "MCSN" (4 bytes)
+//	version major version (1 byte)
+//	header  length, followed by the header fields: entry count
+//	records length, followed by the record fields: expiration (Unix nanoseconds), key length, key, value length, value
+//	end     zero length record
+//
+// Compatibility rules: new fields are only ever appended to the header and to records, and readers skip the
+// trailing bytes they do not know about. Any incompatible change bumps the major version, which readers reject.
+// The entry count is a hint for preallocation, the records are read until the end marker.
+const (
+	snapshotMagic   = "MCSN"
+	snapshotVersion = 1
+)
+
+// gzipMagic starts gzip-compressed snapshots
+var gzipMagic = []byte{0x1f, 0x8b}
+
+var (
+	// ErrBadSnapshot is returned when the snapshot data is malformed.
+	ErrBadSnapshot = errors.New("mcache: malformed snapshot")
+	// ErrSnapshotVersion is returned when the snapshot was written by an incompatible version.
+	ErrSnapshotVersion = errors.New("mcache: unsupported snapshot version")
+)
+
+// SnapshotSink is the destination of background snapshots made with WithSnapshot.
+// Save is called with the function writing a snapshot, the sink is responsible for handling the errors.
+type SnapshotSink interface {
+	Save(write func(io.Writer) error) error
+}
+
+// FileSink is a SnapshotSink that writes snapshots to the file, replacing it atomically.
+type FileSink string
+
+// Save writes the snapshot to a temporary file, and then renames it to the sink path.
+func (path FileSink) Save(write func(io.Writer) error) error {
+	f, err := os.CreateTemp(filepath.Dir(string(path)), filepath.Base(string(path))+".tmp*")
+	if err != nil {
+		return err
+	}
+
+	if err = write(f); err == nil {
+		err = f.Sync()
+	}
+
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+
+	if err == nil {
+		err = os.Rename(f.Name(), string(path))
+	}
+
+	if err != nil {
+		_ = os.Remove(f.Name())
+	}
+
+	return err
+}
+
+// WriteSnapshot writes the cache contents to w in the binary snapshot format.
+// Items are streamed in chunks, so the cache is neither copied as a whole nor locked for the entire write.
+// Strings, byte slices, booleans, and numbers are stored as is, types implementing both encoding.BinaryMarshaler
+// and encoding.BinaryUnmarshaler use them, and other types are gob-encoded.
+func (c *Cache[K, V]) WriteSnapshot(w io.Writer) error {
+	if c.opts.compress {
+		gz := gzip.NewWriter(w)
+
+		if err := c.writeSnapshot(gz); err != nil {
+			return err
+		}
+
+		return gz.Close()
+	}
+
+	return c.writeSnapshot(w)
+}
+
+func (c *Cache[K, V]) writeSnapshot(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	bw.WriteString(snapshotMagic)
+	bw.WriteByte(snapshotVersion)
+
+	var header []byte
+
+	header = binary.AppendUvarint(header, uint64(c.Len()))
+
+	writeBytes(bw, header)
+
+	var record []byte
+
+	if err := c.stream(func(entry snapshotEntry[K, V]) error {
+		record = binary.AppendVarint(record[:0], entry.Expires.UnixNano())
+
+		key, err := encodeValue(entry.Key)
+		if err != nil {
+			return err
+		}
+
+		value, err := encodeValue(entry.Value)
+		if err != nil {
+			return err
+		}
+
+		record = binary.AppendUvarint(record, uint64(len(key)))
+		record = append(record, key...)
+		record = binary.AppendUvarint(record, uint64(len(value)))
+		record = append(record, value...)
+
+		writeBytes(bw, record)
+
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	writeBytes(bw, nil)
+
+	return bw.Flush()
+}
+
+// ReadSnapshot stores items read from the snapshot written by WriteSnapshot. Items that have already expired are skipped.
+// Items are stored in chunks as they are read, so on error some of them may have been stored.
+// Compressed snapshots are detected automatically.
+func (c *Cache[K, V]) ReadSnapshot(r io.Reader) error {
+	br := bufio.NewReader(r)
+
+	if magic, _ := br.Peek(2); bytes.Equal(magic, gzipMagic) {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return ErrBadSnapshot
+		}
+
+		defer gz.Close()
+
+		br = bufio.NewReader(gz)
+	}
+
+	magic := make([]byte, len(snapshotMagic)+1)
+
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic[:len(snapshotMagic)]) != snapshotMagic {
+		return ErrBadSnapshot
+	}
+
+	if magic[len(snapshotMagic)] != snapshotVersion {
+		return fmt.Errorf("%w %d", ErrSnapshotVersion, magic[len(snapshotMagic)])
+	}
+
+	header, err := readBytes(br)
+	if err != nil {
+		return err
+	}
+
+	count, _ := binary.Uvarint(header)
+	if count > getManyChunk {
+		count = getManyChunk
+	}
+
+	entries := make([]snapshotEntry[K, V], 0, count)
+
+	for {
+		record, err := readBytes(br)
+		if err != nil {
+			return err
+		}
+
+		if len(record) == 0 {
+			break
+		}
+
+		entry, err := decodeRecord[K, V](record)
+		if err != nil {
+			return err
+		}
+
+		if entries = append(entries, entry); len(entries) == getManyChunk {
+			c.restore(entries)
+			entries = entries[:0]
+		}
+	}
+
+	c.restore(entries)
+
+	return nil
+}
+
+// SaveToFile writes the binary snapshot to a temporary file, and then renames it to path,
+// so the existing snapshot is never left partially written.
+func (c *Cache[K, V]) SaveToFile(path string) error {
+	return FileSink(path).Save(c.WriteSnapshot)
+}
+
+// LoadFromFile stores items from the binary snapshot file written by SaveToFile. Items that have already expired are skipped.
+func (c *Cache[K, V]) LoadFromFile(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	return c.ReadSnapshot(f)
+}
+
+// snapshotter periodically saves snapshots to the sink until the cache is closed, saving the final one on exit.
+func (c *Cache[K, V]) snapshotter() {
+	defer close(c.closed)
+
+	t := newClockTicker(c.opts.clock, c.opts.snapshotEvery)
+	defer t.stop()
+
+	for {
+		select {
+		case <-t.C:
+			_ = c.opts.snapshotSink.Save(c.WriteSnapshot)
+
+			t.next()
+		case <-c.closing:
+			_ = c.opts.snapshotSink.Save(c.WriteSnapshot)
+
+			return
+		}
+	}
+}
+
+// decodeRecord decodes a single snapshot record, ignoring unknown trailing fields.
+func decodeRecord[K comparable, V any](record []byte) (entry snapshotEntry[K, V], err error) {
+	nanos, n := binary.Varint(record)
+	if n <= 0 {
+		return entry, ErrBadSnapshot
+	}
+
+	record = record[n:]
+
+	key, record, err := nextField(record)
+	if err != nil {
+		return entry, err
+	}
+
+	value, _, err := nextField(record)
+	if err != nil {
+		return entry, err
+	}
+
+	if err = decodeValue(key, &entry.Key); err != nil {
+		return entry, err
+	}
+
+	if err = decodeValue(value, &entry.Value); err != nil {
+		return entry, err
+	}
+
+	entry.Expires = time.Unix(0, nanos)
+
+	return entry, nil
+}
+
+// nextField splits the length-prefixed field off the record.
+func nextField(record []byte) ([]byte, []byte, error) {
+	size, n := binary.Uvarint(record)
+	if n <= 0 || size > uint64(len(record)-n) {
+		return nil, nil, ErrBadSnapshot
+	}
+
+	return record[n : n+int(size)], record[n+int(size):], nil
+}
+
+// writeBytes writes the length-prefixed data. Write errors are reported by the final flush.
+func writeBytes(w *bufio.Writer, data []byte) {
+	var size [binary.MaxVarintLen64]byte
+
+	w.Write(size[:binary.PutUvarint(size[:], uint64(len(data)))])
+	w.Write(data)
+}
+
+// readBytes reads the length-prefixed data.
+func readBytes(r *bufio.Reader) ([]byte, error) {
+	size, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, ErrBadSnapshot
+	}
+
+	if size > math.MaxInt32 {
+		return nil, ErrBadSnapshot
+	}
+
+	data := make([]byte, size)
+
+	if _, err = io.ReadFull(r, data); err != nil {
+		return nil, ErrBadSnapshot
+	}
+
+	return data, nil
+}
+
+// binaryCodec is implemented by types that can be stored in the binary form on their own
+type binaryCodec interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+// encodeValue serializes a key or a value for the binary snapshot.
+func encodeValue[T any](v T) ([]byte, error) {
+	if m, ok := any(&v).(binaryCodec); ok {
+		return m.MarshalBinary()
+	}
+
+	rv := reflect.ValueOf(&v).Elem()
+
+	switch rv.Kind() {
+	case reflect.String:
+		return []byte(rv.String()), nil
+	case reflect.Bool:
+		if rv.Bool() {
+			return []byte{1}, nil
+		}
+
+		return []byte{0}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return binary.AppendVarint(nil, rv.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return binary.AppendUvarint(nil, rv.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return binary.AppendUvarint(nil, math.Float64bits(rv.Float())), nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return rv.Bytes(), nil
+		}
+	}
+
+	var buf bytes.Buffer
+
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// decodeValue deserializes a key or a value written by encodeValue.
+func decodeValue[T any](data []byte, v *T) error {
+	if u, ok := any(v).(binaryCodec); ok {
+		return u.UnmarshalBinary(data)
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+
+	switch rv.Kind() {
+	case reflect.String:
+		rv.SetString(string(data))
+
+		return nil
+	case reflect.Bool:
+		if len(data) != 1 {
+			return ErrBadSnapshot
+		}
+
+		rv.SetBool(data[0] == 1)
+
+		return nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, n := binary.Varint(data)
+		if n != len(data) || n == 0 {
+			return ErrBadSnapshot
+		}
+
+		rv.SetInt(i)
+
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, n := binary.Uvarint(data)
+		if n != len(data) || n == 0 {
+			return ErrBadSnapshot
+		}
+
+		rv.SetUint(u)
+
+		return nil
+	case reflect.Float32, reflect.Float64:
+		u, n := binary.Uvarint(data)
+		if n != len(data) || n == 0 {
+			return ErrBadSnapshot
+		}
+
+		rv.SetFloat(math.Float64frombits(u))
+
+		return nil
+	case reflect.Slice:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			rv.SetBytes(append([]byte(nil), data...))
+
+			return nil
+		}
+	}
+
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
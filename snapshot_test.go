@@ -0,0 +1,323 @@
+package mcache_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/clockmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type point struct {
+	X, Y int
+}
+
+func TestSnapshot(t *testing.T) {
+	c := mcache.New[string, point]()
+
+	c.Set("a", point{1, 2}, 30*time.Millisecond)
+	c.Set("b", point{3, 4}, 40*time.Millisecond)
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.WriteSnapshot(&buf))
+	require.Equal(t, "MCSN\x01", buf.String()[:5])
+
+	d := mcache.New[string, point]()
+
+	require.NoError(t, d.ReadSnapshot(bytes.NewReader(buf.Bytes())))
+	require.Equal(t, []string{"a", "b"}, d.Keys())
+	require.Equal(t, []point{{1, 2}, {3, 4}}, d.Values())
+
+	_, expected, _ := c.GetWithExpiry("b")
+	_, expires, _ := d.GetWithExpiry("b")
+	require.True(t, expected.Equal(expires))
+
+	data := buf.Bytes()
+
+	require.ErrorIs(t, d.ReadSnapshot(bytes.NewReader(data[:len(data)-1])), mcache.ErrBadSnapshot)
+	require.ErrorIs(t, d.ReadSnapshot(bytes.NewReader([]byte("MCSN\x02"))), mcache.ErrSnapshotVersion)
+	require.ErrorIs(t, d.ReadSnapshot(bytes.NewReader([]byte("JUNK\x01"))), mcache.ErrBadSnapshot)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestRestoreKeepsExpiry(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	for i := 0; i < 10; i++ {
+		c.Set(i, i, time.Hour+time.Duration(i)*time.Minute)
+	}
+
+	var snapshot bytes.Buffer
+
+	require.NoError(t, c.WriteSnapshot(&snapshot))
+
+	data, err := c.MarshalJSON()
+	require.NoError(t, err)
+
+	for name, opt := range map[string]mcache.Option{
+		"jitter": mcache.WithTTLJitter(0.5),
+		"limits": mcache.WithTTLLimits(0, time.Minute),
+	} {
+		fromSnapshot := mcache.New[int, int](opt)
+		require.NoError(t, fromSnapshot.ReadSnapshot(bytes.NewReader(snapshot.Bytes())), name)
+
+		fromJSON := mcache.New[int, int](opt)
+		require.NoError(t, fromJSON.UnmarshalJSON(data), name)
+
+		for i := 0; i < 10; i++ {
+			_, want, _ := c.Peek(i)
+
+			for _, d := range []*mcache.Cache[int, int]{fromSnapshot, fromJSON} {
+				_, got, ok := d.Peek(i)
+				require.True(t, ok, name)
+				require.True(t, want.Equal(got), name)
+			}
+		}
+	}
+}
+
+func TestSnapshotTypes(t *testing.T) {
+	now := time.Now()
+
+	ints := mcache.New[int64, float64]()
+	ints.Set(-5, 2.5, 30*time.Millisecond)
+
+	times := mcache.New[uint, time.Time]()
+	times.Set(7, now, 30*time.Millisecond)
+
+	blobs := mcache.New[bool, []byte]()
+	blobs.Set(true, []byte("blob"), 30*time.Millisecond)
+
+	var a, b, c bytes.Buffer
+
+	require.NoError(t, ints.WriteSnapshot(&a))
+	require.NoError(t, times.WriteSnapshot(&b))
+	require.NoError(t, blobs.WriteSnapshot(&c))
+
+	require.NoError(t, ints.ReadSnapshot(&a))
+	require.NoError(t, times.ReadSnapshot(&b))
+	require.NoError(t, blobs.ReadSnapshot(&c))
+
+	f, _ := ints.Get(-5)
+	assert.Equal(t, 2.5, f)
+
+	tm, _ := times.Get(7)
+	assert.True(t, now.Equal(tm))
+
+	blob, _ := blobs.Get(true)
+	assert.Equal(t, []byte("blob"), blob)
+
+	assert.Eventually(t, func() bool {
+		return 0 == ints.Len()+times.Len()+blobs.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestSnapshotForwardCompatible(t *testing.T) {
+	record := binary.AppendVarint(nil, time.Now().Add(30*time.Millisecond).UnixNano())
+	record = append(record, 1, 'k', 1, 'v', 0xff, 0xff) // Unknown trailing field
+
+	data := []byte("MCSN\x01")
+	data = append(data, 3, 1, 0xff, 0xff) // Entry count and unknown trailing header field
+	data = append(data, byte(len(record)))
+	data = append(data, record...)
+	data = append(data, 0)
+
+	c := mcache.New[string, string]()
+
+	require.NoError(t, c.ReadSnapshot(bytes.NewReader(data)))
+
+	v, _ := c.Get("k")
+	require.Equal(t, "v", v)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestSaveToFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cache.snap")
+
+	c := mcache.New[string, int]()
+
+	c.Set("a", 1, 20*time.Millisecond)
+	c.Set("b", 2, 100*time.Millisecond)
+
+	require.NoError(t, c.SaveToFile(path))
+	require.NoError(t, c.SaveToFile(path))
+
+	files, err := os.ReadDir(filepath.Dir(path))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	time.Sleep(30 * time.Millisecond)
+
+	d := mcache.New[string, int]()
+
+	require.NoError(t, d.LoadFromFile(path))
+	require.Equal(t, []string{"b"}, d.Keys())
+
+	require.Error(t, d.LoadFromFile(path+".missing"))
+	require.Error(t, c.SaveToFile(filepath.Join(path, "not-a-dir")))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+type memorySink struct {
+	m     sync.Mutex
+	saves int
+	last  []byte
+}
+
+func (s *memorySink) Save(write func(io.Writer) error) error {
+	var buf bytes.Buffer
+
+	if err := write(&buf); err != nil {
+		return err
+	}
+
+	s.m.Lock()
+	s.saves++
+	s.last = buf.Bytes()
+	s.m.Unlock()
+
+	return nil
+}
+
+func (s *memorySink) Saves() int {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	return s.saves
+}
+
+func TestWithSnapshot(t *testing.T) {
+	sink := &memorySink{}
+
+	c := mcache.New[string, int](mcache.WithSnapshot(10*time.Millisecond, sink))
+
+	c.Set("a", 1, 50*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return sink.Saves() >= 2
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	c.Close()
+	c.Close()
+
+	saves := sink.Saves()
+
+	time.Sleep(30 * time.Millisecond)
+
+	require.Equal(t, saves, sink.Saves())
+
+	d := mcache.New[string, int]()
+
+	require.NoError(t, d.ReadSnapshot(bytes.NewReader(sink.last)))
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWithSnapshotClock(t *testing.T) {
+	clock := clockmock.New(time.Now())
+	sink := &memorySink{}
+
+	c := mcache.New[string, int](mcache.WithClock(clock), mcache.WithSnapshot(time.Minute, sink))
+
+	c.Set("a", 1, time.Hour)
+
+	time.Sleep(10 * time.Millisecond)
+	require.Zero(t, sink.Saves())
+
+	clock.Advance(time.Minute)
+
+	assert.Eventually(t, func() bool {
+		return sink.Saves() == 1
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		clock.Advance(time.Minute)
+
+		return sink.Saves() >= 2
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	c.Close()
+}
+
+func TestSnapshotStreaming(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	for i := 0; i < 2500; i++ {
+		c.Set(i, i, 50*time.Millisecond)
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for i := 0; i < 2500; i += 2 {
+			c.Delete(i)
+		}
+	}()
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.WriteSnapshot(&buf))
+
+	<-done
+
+	d := mcache.New[int, int]()
+
+	require.NoError(t, d.ReadSnapshot(&buf))
+
+	for i := 1; i < 2500; i += 2 {
+		v, ok := d.Get(i)
+		require.True(t, ok)
+		require.Equal(t, i, v)
+	}
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestCompressedSnapshot(t *testing.T) {
+	c := mcache.New[int, string](mcache.WithCompressedSnapshots())
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, strings.Repeat("value", 100), 50*time.Millisecond)
+	}
+
+	var buf bytes.Buffer
+
+	require.NoError(t, c.WriteSnapshot(&buf))
+	require.Equal(t, []byte{0x1f, 0x8b}, buf.Bytes()[:2])
+	require.Less(t, buf.Len(), 100*500/10)
+
+	d := mcache.New[int, string]()
+
+	require.NoError(t, d.ReadSnapshot(&buf))
+	require.Equal(t, 100, d.Len())
+	require.ErrorIs(t, d.ReadSnapshot(bytes.NewReader([]byte{0x1f, 0x8b, 0})), mcache.ErrBadSnapshot)
+
+	assert.Eventually(t, func() bool {
+		return 0 == c.Len()+d.Len()
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,62 @@
+package mcache
+
+import "sync/atomic"
+
+// Stats holds cache operation counters.
+type Stats struct {
+	Hits        uint64 // Number of lookups that found the key
+	Misses      uint64 // Number of lookups that did not find the key
+	Sets        uint64 // Number of stored values
+	Deletes     uint64 // Number of keys deleted explicitly
+	Expirations uint64 // Number of items removed because they have expired
+	Evictions   uint64 // Number of items removed with Evict or to fit the memory budget set with WithMemoryBudget
+	Dropped     uint64 // Number of events dropped because the Events channel buffer was full
+	Rejected    uint64 // Number of values not stored because they exceeded the size set with WithMaxValueSize
+}
+
+type cacheStats struct {
+	hits        atomic.Uint64
+	misses      atomic.Uint64
+	sets        atomic.Uint64
+	deletes     atomic.Uint64
+	expirations atomic.Uint64
+	evictions   atomic.Uint64
+
+	droppedEvents atomic.Uint64
+	rejected      atomic.Uint64
+}
+
+// Stats returns cache operation counters collected since the cache creation or the last ResetStats call.
+func (c *Cache[K, V]) Stats() Stats {
+	return Stats{
+		Hits:        c.stats.hits.Load(),
+		Misses:      c.stats.misses.Load(),
+		Sets:        c.stats.sets.Load(),
+		Deletes:     c.stats.deletes.Load(),
+		Expirations: c.stats.expirations.Load(),
+		Evictions:   c.stats.evictions.Load(),
+		Dropped:     c.stats.droppedEvents.Load(),
+		Rejected:    c.stats.rejected.Load(),
+	}
+}
+
+// ResetStats sets all cache operation counters to zero.
+func (c *Cache[K, V]) ResetStats() {
+	c.stats.hits.Store(0)
+	c.stats.misses.Store(0)
+	c.stats.sets.Store(0)
+	c.stats.deletes.Store(0)
+	c.stats.expirations.Store(0)
+	c.stats.evictions.Store(0)
+	c.stats.droppedEvents.Store(0)
+	c.stats.rejected.Store(0)
+}
+
+// lookup records the result of a key lookup.
+func (s *cacheStats) lookup(hit bool) {
+	if hit {
+		s.hits.Add(1)
+	} else {
+		s.misses.Add(1)
+	}
+}
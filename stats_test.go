@@ -0,0 +1,44 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	c.Set(1, 1, 20*time.Millisecond)
+	c.Set(2, 2, 20*time.Millisecond)
+	c.SetMany(map[int]int{3: 3, 4: 4}, 20*time.Millisecond)
+
+	c.Get(1)
+	c.Get(5)
+	c.MGet(1, 2, 6)
+	c.GetMany(3, 7)
+	c.Has(1)
+
+	c.Delete(2)
+	c.Delete(2)
+	c.Evict(1)
+
+	require.Equal(t, mcache.Stats{
+		Hits:      4,
+		Misses:    3,
+		Sets:      4,
+		Deletes:   1,
+		Evictions: 1,
+	}, c.Stats())
+
+	assert.Eventually(t, func() bool {
+		return c.Stats().Expirations == 2
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	c.ResetStats()
+
+	require.Equal(t, mcache.Stats{}, c.Stats())
+}
@@ -0,0 +1,69 @@
+package mcache
+
+import (
+	"context"
+	"time"
+)
+
+// Store is a backing store, such as a database, holding values behind the cache.
+// Get must return ErrNotFound if the key is not present.
+type Store[K comparable, V any] interface {
+	Get(ctx context.Context, key K) (V, error)
+	Set(ctx context.Context, key K, value V) error
+	Delete(ctx context.Context, key K) error
+}
+
+// WriteThrough wraps a cache, writing every change to the backing store before changing the cache,
+// and loading missing values from the store.
+type WriteThrough[K comparable, V any] struct {
+	cache *Cache[K, V]
+	store Store[K, V]
+	ttl   time.Duration
+}
+
+// NewWriteThrough creates a cache wrapper writing through to the store. Values loaded from the store are cached for ttl.
+func NewWriteThrough[K comparable, V any](c *Cache[K, V], store Store[K, V], ttl time.Duration) *WriteThrough[K, V] {
+	return &WriteThrough[K, V]{
+		cache: c,
+		store: store,
+		ttl:   ttl,
+	}
+}
+
+// Get returns the value from the cache, or loads it from the store and caches it.
+// Concurrent misses of the same key share a single load. Returns ErrNotFound if the store does not have the key.
+func (w *WriteThrough[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return w.cache.GetOrCompute(key, func() (V, time.Duration, error) {
+		value, err := w.store.Get(ctx, key)
+
+		return value, w.ttl, err
+	})
+}
+
+// Set writes the value to the store, then caches it with the given TTL.
+// If the store fails, the key is removed from the cache, so it does not serve a value the store does not have.
+func (w *WriteThrough[K, V]) Set(ctx context.Context, key K, value V, ttl time.Duration) error {
+	if err := w.store.Set(ctx, key, value); err != nil {
+		w.cache.Delete(key)
+
+		return err
+	}
+
+	w.cache.Set(key, value, ttl)
+
+	return nil
+}
+
+// Delete deletes the key from the store and the cache.
+func (w *WriteThrough[K, V]) Delete(ctx context.Context, key K) error {
+	err := w.store.Delete(ctx, key)
+
+	w.cache.Delete(key)
+
+	return err
+}
+
+// Len returns number of items in the cache.
+func (w *WriteThrough[K, V]) Len() int {
+	return w.cache.Len()
+}
@@ -0,0 +1,292 @@
+package mcache_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// mapStore is a backing store keeping values in a map, optionally failing writes.
+type mapStore struct {
+	values map[string]int
+	reads  int
+	fail   error
+	gate   chan struct{} // Blocks writes until closed, if set
+	m      sync.Mutex
+}
+
+func newMapStore() *mapStore {
+	return &mapStore{values: make(map[string]int)}
+}
+
+func (s *mapStore) Get(_ context.Context, key string) (int, error) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	s.reads++
+
+	value, ok := s.values[key]
+	if !ok {
+		return 0, mcache.ErrNotFound
+	}
+
+	return value, nil
+}
+
+func (s *mapStore) Set(_ context.Context, key string, value int) error {
+	if s.gate != nil {
+		<-s.gate
+	}
+
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.fail != nil {
+		return s.fail
+	}
+
+	s.values[key] = value
+
+	return nil
+}
+
+func (s *mapStore) Delete(_ context.Context, key string) error {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	if s.fail != nil {
+		return s.fail
+	}
+
+	delete(s.values, key)
+
+	return nil
+}
+
+func (s *mapStore) get(key string) (int, bool) {
+	s.m.Lock()
+	defer s.m.Unlock()
+
+	value, ok := s.values[key]
+
+	return value, ok
+}
+
+func (s *mapStore) failWith(err error) {
+	s.m.Lock()
+	s.fail = err
+	s.m.Unlock()
+}
+
+func TestWriteThrough(t *testing.T) {
+	ctx := context.Background()
+	store := newMapStore()
+	store.values["cold"] = 1
+
+	c := mcache.New[string, int]()
+	w := mcache.NewWriteThrough[string, int](c, store, 20*time.Millisecond)
+
+	value, err := w.Get(ctx, "cold")
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+
+	value, err = w.Get(ctx, "cold")
+	require.NoError(t, err)
+	require.Equal(t, 1, value)
+	require.Equal(t, 1, store.reads)
+
+	_, err = w.Get(ctx, "missing")
+	require.ErrorIs(t, err, mcache.ErrNotFound)
+
+	require.NoError(t, w.Set(ctx, "hot", 2, 20*time.Millisecond))
+
+	stored, ok := store.get("hot")
+	require.True(t, ok)
+	require.Equal(t, 2, stored)
+	require.True(t, c.Has("hot"))
+
+	// Failed writes do not leave the cached value behind
+	failed := errors.New("failed")
+	store.failWith(failed)
+
+	require.ErrorIs(t, w.Set(ctx, "hot", 3, 20*time.Millisecond), failed)
+	require.False(t, c.Has("hot"))
+
+	store.failWith(nil)
+
+	require.NoError(t, w.Delete(ctx, "cold"))
+	require.False(t, c.Has("cold"))
+
+	_, ok = store.get("cold")
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return w.Len() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+// batchStore is a mapStore writing batches at once.
+type batchStore struct {
+	*mapStore
+	batches int
+}
+
+func (s *batchStore) SetMany(ctx context.Context, values map[string]int) error {
+	for key, value := range values {
+		if err := s.Set(ctx, key, value); err != nil {
+			return err
+		}
+	}
+
+	s.m.Lock()
+	s.batches++
+	s.m.Unlock()
+
+	return nil
+}
+
+func (s *batchStore) DeleteMany(ctx context.Context, keys []string) error {
+	for _, key := range keys {
+		if err := s.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func TestWriteBehind(t *testing.T) {
+	ctx := context.Background()
+	store := newMapStore()
+	store.values["cold"] = 1
+
+	c := mcache.New[string, int]()
+	w := mcache.NewWriteBehind[string, int](c, store, 20*time.Millisecond, time.Hour, 100)
+
+	defer w.Close()
+
+	w.Set("a", 1, 20*time.Millisecond)
+	w.Set("a", 2, 20*time.Millisecond)
+	w.Set("b", 3, 20*time.Millisecond)
+	w.Delete("cold")
+
+	require.Equal(t, 3, w.Pending())
+
+	_, ok := store.get("a")
+	require.False(t, ok)
+
+	// Pending changes are visible before they are written
+	c.Delete("a")
+
+	value, err := w.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, 2, value)
+
+	_, err = w.Get(ctx, "cold")
+	require.ErrorIs(t, err, mcache.ErrNotFound)
+
+	// Failed changes stay pending
+	failed := errors.New("failed")
+	store.failWith(failed)
+
+	require.ErrorIs(t, w.Flush(ctx), failed)
+	require.Equal(t, 3, w.Pending())
+
+	store.failWith(nil)
+
+	require.NoError(t, w.Flush(ctx))
+	require.Zero(t, w.Pending())
+
+	value, ok = store.get("a")
+	require.True(t, ok)
+	require.Equal(t, 2, value)
+
+	_, ok = store.get("cold")
+	require.False(t, ok)
+
+	assert.Eventually(t, func() bool {
+		return w.Len() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
+
+func TestWriteBehindBackground(t *testing.T) {
+	store := &batchStore{mapStore: newMapStore()}
+
+	w := mcache.NewWriteBehind[string, int](mcache.New[string, int](), store, 0, 10*time.Millisecond, 2)
+
+	defer w.Close()
+
+	w.Set("a", 1, 20*time.Millisecond)
+	w.Set("b", 2, 20*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		_, ok := store.get("b")
+
+		return ok && w.Pending() == 0
+	}, 100*time.Millisecond, time.Millisecond)
+
+	// Failed batches are retried
+	store.failWith(errors.New("failed"))
+	w.Set("c", 3, 20*time.Millisecond)
+
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, 1, w.Pending())
+
+	store.failWith(nil)
+
+	assert.Eventually(t, func() bool {
+		_, ok := store.get("c")
+
+		return ok && w.Pending() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+
+	store.m.Lock()
+	require.GreaterOrEqual(t, store.batches, 2)
+	store.m.Unlock()
+}
+
+func TestWriteBehindInFlight(t *testing.T) {
+	ctx := context.Background()
+	store := newMapStore()
+	store.values["a"] = 1
+	store.gate = make(chan struct{})
+
+	c := mcache.New[string, int]()
+
+	// Non-positive interval is replaced with the default one
+	w := mcache.NewWriteBehind[string, int](c, store, 20*time.Millisecond, 0, 100)
+
+	defer w.Close()
+
+	w.Set("a", 2, 20*time.Millisecond)
+
+	flushed := make(chan error)
+
+	go func() {
+		flushed <- w.Flush(ctx)
+	}()
+
+	// The change being written is still served instead of the old value from the store
+	time.Sleep(5 * time.Millisecond)
+	c.Delete("a")
+
+	value, err := w.Get(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, 2, value)
+	require.Equal(t, 1, w.Pending())
+
+	close(store.gate)
+	require.NoError(t, <-flushed)
+	require.Zero(t, w.Pending())
+
+	stored, _ := store.get("a")
+	require.Equal(t, 2, stored)
+
+	c.Clear()
+}
@@ -0,0 +1,220 @@
+/*
+Package stresstest runs concurrent operations against a cache implementation
+and verifies the observed results are consistent with a linearizable key/value store.
+
+It is meant for testing cache wrappers and alternative implementations:
+
+	func TestMyCache(t *testing.T) {
+		require.NoError(t, stresstest.Run(NewMyCache[int, int](), stresstest.Config{}))
+	}
+*/
+package stresstest
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+)
+
+// Config defines the load to run. Zero fields are set to defaults.
+type Config struct {
+	Goroutines int           // Number of concurrent goroutines, default 8
+	Ops        int           // Number of operations per goroutine, default 1000
+	Keys       int           // Number of distinct keys, default 16
+	Gets       int           // Relative weight of Get operations, default 6
+	Sets       int           // Relative weight of Set operations, default 3
+	Deletes    int           // Relative weight of Delete operations, default 1
+	TTL        time.Duration // TTL of stored values, must be longer than the test run, default one minute
+	Seed       int64         // Random seed, default is based on the current time
+}
+
+type kind int
+
+const (
+	get kind = iota
+	set
+	del
+)
+
+// op is a single recorded operation. Start and End are logical timestamps.
+type op struct {
+	Kind    kind
+	Key     int
+	Value   int // Written or read value, values are unique and never zero
+	Present bool
+	Start   int64
+	End     int64
+}
+
+// Run runs the load against the cache and checks the history of operations.
+// Values written are unique positive integers, so the cache must be empty or only hold keys outside [0, Keys).
+// Returns error describing the first inconsistency found.
+func Run(c mcache.Cacher[int, int], cfg Config) error {
+	cfg.defaults()
+
+	var (
+		clock atomic.Int64
+		wg    sync.WaitGroup
+	)
+
+	history := make([][]op, cfg.Goroutines)
+
+	for g := 0; g < cfg.Goroutines; g++ {
+		wg.Add(1)
+
+		go func(g int) {
+			defer wg.Done()
+
+			rnd := rand.New(rand.NewSource(cfg.Seed + int64(g)))
+			ops := make([]op, 0, cfg.Ops)
+
+			for i := 0; i < cfg.Ops; i++ {
+				o := op{Key: rnd.Intn(cfg.Keys)}
+
+				switch n := rnd.Intn(cfg.Gets + cfg.Sets + cfg.Deletes); {
+				case n < cfg.Gets:
+					o.Kind = get
+					o.Start = clock.Add(1)
+					o.Value, o.Present = c.Get(o.Key)
+					o.End = clock.Add(1)
+				case n < cfg.Gets+cfg.Sets:
+					o.Kind = set
+					o.Value, o.Present = g*cfg.Ops+i+1, true
+					o.Start = clock.Add(1)
+					c.Set(o.Key, o.Value, cfg.TTL)
+					o.End = clock.Add(1)
+				default:
+					o.Kind = del
+					o.Start = clock.Add(1)
+					c.Delete(o.Key)
+					o.End = clock.Add(1)
+				}
+
+				ops = append(ops, o)
+			}
+
+			history[g] = ops
+		}(g)
+	}
+
+	wg.Wait()
+
+	return check(history, cfg.Keys)
+}
+
+func (cfg *Config) defaults() {
+	if cfg.Goroutines <= 0 {
+		cfg.Goroutines = 8
+	}
+
+	if cfg.Ops <= 0 {
+		cfg.Ops = 1000
+	}
+
+	if cfg.Keys <= 0 {
+		cfg.Keys = 16
+	}
+
+	if cfg.Gets <= 0 && cfg.Sets <= 0 && cfg.Deletes <= 0 {
+		cfg.Gets, cfg.Sets, cfg.Deletes = 6, 3, 1
+	}
+
+	if cfg.TTL <= 0 {
+		cfg.TTL = time.Minute
+	}
+
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+}
+
+// check verifies, for every key, that each read returns a value that was written before the read ended,
+// that was not overwritten before the read started, and that reads do not go back in time.
+func check(history [][]op, keys int) error {
+	writes := make([][]op, keys)
+	reads := make([][]op, keys)
+
+	for k := range writes {
+		// Initially all keys are absent
+		writes[k] = []op{{Kind: del, Key: k}}
+	}
+
+	for _, ops := range history {
+		for _, o := range ops {
+			if o.Kind == get {
+				reads[o.Key] = append(reads[o.Key], o)
+			} else {
+				writes[o.Key] = append(writes[o.Key], o)
+			}
+		}
+	}
+
+	for k := range reads {
+		source := make([]op, len(reads[k]))
+
+		for i, r := range reads[k] {
+			w, err := findWrite(r, writes[k])
+			if err != nil {
+				return err
+			}
+
+			source[i] = w
+		}
+
+		for i, r1 := range reads[k] {
+			for j, r2 := range reads[k] {
+				if r1.End < r2.Start && r1.Present && r2.Present && source[j].End < source[i].Start {
+					return fmt.Errorf("key %d: read of %d followed by read of older value %d", k, r1.Value, r2.Value)
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// findWrite returns the write the read could have observed.
+func findWrite(r op, writes []op) (op, error) {
+	for _, w := range writes {
+		if w.Present != r.Present || (r.Present && w.Value != r.Value) {
+			continue
+		}
+
+		if w.Start > r.End {
+			if r.Present {
+				return w, fmt.Errorf("key %d: value %d read before it was written", r.Key, r.Value)
+			}
+
+			continue
+		}
+
+		if !overwritten(w, r, writes) {
+			return w, nil
+		}
+
+		if r.Present {
+			return w, fmt.Errorf("key %d: stale value %d read after it was overwritten", r.Key, r.Value)
+		}
+	}
+
+	if r.Present {
+		return op{}, fmt.Errorf("key %d: value %d read, but never written", r.Key, r.Value)
+	}
+
+	return op{}, fmt.Errorf("key %d: missing value read, while it should be present", r.Key)
+}
+
+// overwritten reports whether some other write happened entirely between w and r.
+func overwritten(w, r op, writes []op) bool {
+	for _, o := range writes {
+		if w.End < o.Start && o.End < r.Start {
+			return true
+		}
+	}
+
+	return false
+}
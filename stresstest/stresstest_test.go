@@ -0,0 +1,46 @@
+package stresstest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/dmytro-vovk/go-mcache/stresstest"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestRun(t *testing.T) {
+	c := mcache.New[int, int]()
+
+	require.NoError(t, stresstest.Run(c, stresstest.Config{TTL: 100 * time.Millisecond}))
+
+	require.Eventually(t, func() bool {
+		return 0 == c.Len()
+	}, 300*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestRunBroken(t *testing.T) {
+	require.Error(t, stresstest.Run(&broken{}, stresstest.Config{Goroutines: 1}))
+}
+
+// broken cache never forgets the first value set
+type broken struct {
+	value int
+	set   bool
+}
+
+func (b *broken) Set(_ int, value int, _ time.Duration) {
+	if !b.set {
+		b.value, b.set = value, true
+	}
+}
+
+func (b *broken) Get(int) (int, bool)             { return b.value, b.set }
+func (b *broken) Delete(int) bool                 { return false }
+func (b *broken) Refresh(int, time.Duration) bool { return false }
+func (b *broken) Len() int                        { return 0 }
@@ -0,0 +1,83 @@
+package mcache
+
+import "time"
+
+// Tiered chains two caches, such as a small fast L1 in front of a large sharded L2.
+// Values are written to both tiers, and values found in L2 only are promoted to L1.
+type Tiered[K comparable, V any] struct {
+	l1, l2 Cacher[K, V]
+	l1TTL  time.Duration
+}
+
+// NewTiered creates a cache made of two tiers. Values are kept in L1 for at most l1TTL, zero l1TTL keeps them
+// for their full TTL. Promoted values get the remaining TTL of L2, if L2 reports it, as Cache and Sharded do.
+func NewTiered[K comparable, V any](l1, l2 Cacher[K, V], l1TTL time.Duration) *Tiered[K, V] {
+	return &Tiered[K, V]{
+		l1:    l1,
+		l2:    l2,
+		l1TTL: l1TTL,
+	}
+}
+
+// Set adds or replaces the value in both tiers.
+func (t *Tiered[K, V]) Set(key K, value V, ttl time.Duration) {
+	t.l2.Set(key, value, ttl)
+	t.l1.Set(key, value, t.capTTL(ttl))
+}
+
+// Get returns the value from L1, or from L2 promoting it to L1.
+func (t *Tiered[K, V]) Get(key K) (V, bool) {
+	if value, ok := t.l1.Get(key); ok {
+		return value, true
+	}
+
+	value, ok := t.l2.Get(key)
+	if !ok {
+		return value, false
+	}
+
+	ttl := t.l1TTL
+
+	if l2, ok := t.l2.(interface {
+		TTL(K) (time.Duration, bool)
+	}); ok {
+		if remaining, ok := l2.TTL(key); ok {
+			ttl = t.capTTL(remaining)
+		}
+	}
+
+	if ttl > 0 {
+		t.l1.Set(key, value, ttl)
+	}
+
+	return value, true
+}
+
+// Delete deletes the key from both tiers, reporting whether it was present in either.
+func (t *Tiered[K, V]) Delete(key K) bool {
+	ok1 := t.l1.Delete(key)
+	ok2 := t.l2.Delete(key)
+
+	return ok1 || ok2
+}
+
+// Refresh sets new TTL for the key in both tiers, reporting whether the key was present in L2.
+func (t *Tiered[K, V]) Refresh(key K, ttl time.Duration) bool {
+	t.l1.Refresh(key, t.capTTL(ttl))
+
+	return t.l2.Refresh(key, ttl)
+}
+
+// Len returns number of items in L2.
+func (t *Tiered[K, V]) Len() int {
+	return t.l2.Len()
+}
+
+// capTTL limits the TTL of values in L1.
+func (t *Tiered[K, V]) capTTL(ttl time.Duration) time.Duration {
+	if t.l1TTL > 0 && ttl > t.l1TTL {
+		return t.l1TTL
+	}
+
+	return ttl
+}
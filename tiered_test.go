@@ -0,0 +1,56 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTiered(t *testing.T) {
+	l1 := mcache.New[string, int]()
+	l2 := mcache.NewSharded[string, int](4)
+
+	defer l2.Close()
+
+	c := mcache.NewTiered[string, int](l1, l2, 10*time.Millisecond)
+
+	c.Set("a", 1, 50*time.Millisecond)
+
+	ttl, ok := l1.TTL("a")
+	require.True(t, ok)
+	require.LessOrEqual(t, ttl, 10*time.Millisecond)
+	require.True(t, l2.Has("a"))
+
+	// Values found in L2 are promoted
+	l2.Set("b", 2, 50*time.Millisecond)
+
+	value, ok := c.Get("b")
+	require.True(t, ok)
+	require.Equal(t, 2, value)
+	require.True(t, l1.Has("b"))
+
+	assert.Eventually(t, func() bool {
+		return !l1.Has("a")
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	value, ok = c.Get("a")
+	require.True(t, ok)
+	require.Equal(t, 1, value)
+	require.True(t, l1.Has("a"))
+
+	_, ok = c.Get("missing")
+	require.False(t, ok)
+
+	require.Equal(t, 2, c.Len())
+	require.True(t, c.Refresh("a", 20*time.Millisecond))
+	require.True(t, c.Delete("b"))
+	require.False(t, l1.Has("b"))
+	require.False(t, c.Delete("b"))
+
+	assert.Eventually(t, func() bool {
+		return c.Len()+l1.Len() == 0
+	}, 200*time.Millisecond, 10*time.Millisecond)
+}
@@ -0,0 +1,66 @@
+package mcache
+
+import "time"
+
+// GetVersioned works as Get, also returning the version of the value. Versions grow every time a value is stored
+// in the cache, by any operation changing it, so they can be used with SetIfVersion for optimistic concurrency.
+// Refreshing TTL does not change the version.
+func (c *Cache[K, V]) GetVersioned(key K) (V, uint64, bool) {
+	c.m.RLock()
+
+	v, ok := c.cache[key]
+	if !ok || !c.alive(v.Ptr) {
+		c.m.RUnlock()
+
+		c.stats.lookup(false)
+
+		var zero V
+
+		return zero, 0, false
+	}
+
+	version := v.Ptr.Version
+
+	c.accessed(v.Ptr)
+
+	c.m.RUnlock()
+
+	c.stats.lookup(true)
+
+	return v.Value, version, true
+}
+
+// SetIfVersion stores the value with the given TTL only if the current version of the key matches the given one,
+// returning the new version and true, or the current version and false otherwise.
+// Version zero matches missing keys only. Rejected values are not stored, and false is returned.
+func (c *Cache[K, V]) SetIfVersion(key K, value V, ttl time.Duration, version uint64) (uint64, bool) {
+	expires := c.now().Add(ttl)
+
+	c.m.Lock()
+
+	var current uint64
+
+	if v, ok := c.cache[key]; ok && c.alive(v.Ptr) {
+		current = v.Ptr.Version
+	}
+
+	if current != version {
+		c.m.Unlock()
+
+		return current, false
+	}
+
+	c.set(key, value, expires, c.opts.precision, false)
+
+	// The value could have been rejected, e.g. by WithMaxValueSize
+	v, stored := c.cache[key]
+	if stored {
+		current = v.Ptr.Version
+	} else {
+		current = 0
+	}
+
+	c.unlock()
+
+	return current, stored
+}
@@ -0,0 +1,64 @@
+package mcache_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVersions(t *testing.T) {
+	c := mcache.New[string, string]()
+
+	_, _, ok := c.GetVersioned("a")
+	require.False(t, ok)
+
+	// Version zero only matches missing keys
+	v1, ok := c.SetIfVersion("a", "one", 20*time.Millisecond, 0)
+	require.True(t, ok)
+	require.NotZero(t, v1)
+
+	current, ok := c.SetIfVersion("a", "two", 20*time.Millisecond, 0)
+	require.False(t, ok)
+	require.Equal(t, v1, current)
+
+	value, version, ok := c.GetVersioned("a")
+	require.True(t, ok)
+	require.Equal(t, "one", value)
+	require.Equal(t, v1, version)
+
+	// Refreshing does not change the version, any update does
+	require.True(t, c.Refresh("a", 20*time.Millisecond))
+
+	_, version, _ = c.GetVersioned("a")
+	require.Equal(t, v1, version)
+
+	require.True(t, c.Update("a", "two"))
+
+	_, v2, _ := c.GetVersioned("a")
+	require.Greater(t, v2, v1)
+
+	current, ok = c.SetIfVersion("a", "three", 20*time.Millisecond, v1)
+	require.False(t, ok)
+	require.Equal(t, v2, current)
+
+	v3, ok := c.SetIfVersion("a", "three", 20*time.Millisecond, v2)
+	require.True(t, ok)
+	require.Greater(t, v3, v2)
+
+	value, _, _ = c.GetVersioned("a")
+	require.Equal(t, "three", value)
+
+	// Rejected values are not stored
+	s := mcache.New[string, string](mcache.WithMaxValueSize[string](3, nil))
+
+	_, ok = s.SetIfVersion("a", "long", 20*time.Millisecond, 0)
+	require.False(t, ok)
+	require.False(t, s.Has("a"))
+
+	assert.Eventually(t, func() bool {
+		return c.Len() == 0
+	}, 100*time.Millisecond, 10*time.Millisecond)
+}
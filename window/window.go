@@ -0,0 +1,125 @@
+/*
+Package window implements per-key sliding window counters on top of mcache.
+
+Each key keeps count and sum of values added during the last window, split into buckets.
+Keys not updated for the whole window expire automatically.
+*/
+package window
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache"
+)
+
+// Counter keeps sliding window counters for keys.
+type Counter[K comparable] struct {
+	c       *mcache.Cache[K, *series]
+	window  time.Duration
+	bucket  time.Duration
+	buckets int
+}
+
+// series holds buckets of a single key
+type series struct {
+	slots  []int64 // Time slot number each bucket holds
+	counts []int64
+	sums   []float64
+	m      sync.Mutex
+}
+
+// New creates counters over the window of given size, split into the given number of buckets.
+// Panics if the number of buckets is not positive, or the window is too small to be split into them.
+func New[K comparable](window time.Duration, buckets int) *Counter[K] {
+	if buckets <= 0 || window/time.Duration(buckets) <= 0 {
+		panic("window: invalid window size or number of buckets")
+	}
+
+	return &Counter[K]{
+		c:       mcache.New[K, *series](),
+		window:  window,
+		bucket:  window / time.Duration(buckets),
+		buckets: buckets,
+	}
+}
+
+// Inc adds one to the key count.
+func (w *Counter[K]) Inc(key K) {
+	w.Add(key, 1)
+}
+
+// Add adds the value to the key sum, and one to the key count.
+func (w *Counter[K]) Add(key K, value float64) {
+	s, _ := w.c.Compute(key, func(s *series, ok bool) (*series, time.Duration, bool) {
+		if !ok {
+			s = &series{
+				slots:  make([]int64, w.buckets),
+				counts: make([]int64, w.buckets),
+				sums:   make([]float64, w.buckets),
+			}
+		}
+
+		return s, w.window, false
+	})
+
+	slot := w.slot(time.Now())
+	i := int(slot % int64(w.buckets))
+
+	s.m.Lock()
+
+	if s.slots[i] != slot {
+		s.slots[i], s.counts[i], s.sums[i] = slot, 0, 0
+	}
+
+	s.counts[i]++
+	s.sums[i] += value
+
+	s.m.Unlock()
+}
+
+// Count returns number of values added for the key during the window.
+func (w *Counter[K]) Count(key K) int64 {
+	count, _ := w.Get(key)
+
+	return count
+}
+
+// Sum returns sum of values added for the key during the window.
+func (w *Counter[K]) Sum(key K) float64 {
+	_, sum := w.Get(key)
+
+	return sum
+}
+
+// Get returns both count and sum of values added for the key during the window.
+func (w *Counter[K]) Get(key K) (count int64, sum float64) {
+	s, ok := w.c.Get(key)
+	if !ok {
+		return 0, 0
+	}
+
+	slot := w.slot(time.Now())
+
+	s.m.Lock()
+
+	for i := range s.slots {
+		if slot-s.slots[i] < int64(w.buckets) {
+			count += s.counts[i]
+			sum += s.sums[i]
+		}
+	}
+
+	s.m.Unlock()
+
+	return
+}
+
+// Len returns number of keys updated during the window.
+func (w *Counter[K]) Len() int {
+	return w.c.Len()
+}
+
+func (w *Counter[K]) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(w.bucket)
+}
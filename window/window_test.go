@@ -0,0 +1,61 @@
+package window_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/dmytro-vovk/go-mcache/window"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/goleak"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m)
+}
+
+func TestCounter(t *testing.T) {
+	w := window.New[string](100*time.Millisecond, 10)
+
+	w.Inc("a")
+	w.Add("a", 2.5)
+	w.Add("b", 1)
+
+	count, sum := w.Get("a")
+	require.Equal(t, int64(2), count)
+	require.Equal(t, 3.5, sum)
+	require.Equal(t, int64(1), w.Count("b"))
+	require.Equal(t, 1.0, w.Sum("b"))
+	require.Zero(t, w.Count("c"))
+	require.Equal(t, 2, w.Len())
+
+	assert.Eventually(t, func() bool {
+		return 0 == w.Len()
+	}, 300*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestCounterSlides(t *testing.T) {
+	w := window.New[string](100*time.Millisecond, 10)
+
+	w.Inc("a")
+
+	time.Sleep(60 * time.Millisecond)
+
+	w.Inc("a")
+
+	require.Equal(t, int64(2), w.Count("a"))
+
+	assert.Eventually(t, func() bool {
+		return w.Count("a") == 1
+	}, 100*time.Millisecond, 5*time.Millisecond)
+
+	assert.Eventually(t, func() bool {
+		return 0 == w.Len()
+	}, 300*time.Millisecond, 20*time.Millisecond)
+}
+
+func TestNewPanics(t *testing.T) {
+	require.Panics(t, func() {
+		window.New[string](time.Second, 0)
+	})
+}
@@ -0,0 +1,273 @@
+package mcache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchStore is a Store able to write multiple changes at once, WriteBehind uses it when the store implements it.
+type BatchStore[K comparable, V any] interface {
+	Store[K, V]
+	SetMany(ctx context.Context, values map[K]V) error
+	DeleteMany(ctx context.Context, keys []K) error
+}
+
+// Flush interval of WriteBehind created with non-positive interval
+const defaultFlushInterval = time.Second
+
+// WriteBehind wraps a cache, changing the cache right away and writing the changes to the backing store
+// in the background, in batches. Only the latest change of a key is written. Changes stay pending until
+// the store acknowledges them, failed batches are retried on the next flush.
+type WriteBehind[K comparable, V any] struct {
+	cache     *Cache[K, V]
+	store     Store[K, V]
+	ttl       time.Duration
+	batchSize int
+	dirty     map[K]change[V]
+	seq       uint64 // The last assigned change number
+	m         sync.Mutex
+	fm        sync.Mutex // Serializes flushes, so older changes never overwrite newer ones
+	kick      chan struct{}
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// change is a pending write of a key.
+type change[V any] struct {
+	value   V
+	deleted bool
+	seq     uint64 // Tells apart changes of the same key
+}
+
+// NewWriteBehind creates a cache wrapper writing changes to the store every interval, or as soon as
+// batchSize keys are changed. Values loaded from the store are cached for ttl.
+// Non-positive interval is replaced with one second. Close must be called to stop the background flushing.
+func NewWriteBehind[K comparable, V any](
+	c *Cache[K, V],
+	store Store[K, V],
+	ttl, interval time.Duration,
+	batchSize int,
+) *WriteBehind[K, V] {
+	if batchSize < 1 {
+		batchSize = 1
+	}
+
+	if interval <= 0 {
+		interval = defaultFlushInterval
+	}
+
+	w := &WriteBehind[K, V]{
+		cache:     c,
+		store:     store,
+		ttl:       ttl,
+		batchSize: batchSize,
+		dirty:     make(map[K]change[V]),
+		kick:      make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+		done:      make(chan struct{}),
+	}
+
+	go w.run(interval)
+
+	return w
+}
+
+// Get returns the value from the cache, the pending change, or loads the value from the store and caches it.
+// Returns ErrNotFound if the key is not present.
+func (w *WriteBehind[K, V]) Get(ctx context.Context, key K) (V, error) {
+	return w.cache.GetOrCompute(key, func() (V, time.Duration, error) {
+		w.m.Lock()
+		ch, ok := w.dirty[key]
+		w.m.Unlock()
+
+		switch {
+		case ok && ch.deleted:
+			return ch.value, 0, ErrNotFound
+		case ok:
+			return ch.value, w.ttl, nil
+		}
+
+		value, err := w.store.Get(ctx, key)
+
+		return value, w.ttl, err
+	})
+}
+
+// Set caches the value with the given TTL, and queues it to be written to the store.
+func (w *WriteBehind[K, V]) Set(key K, value V, ttl time.Duration) {
+	w.cache.Set(key, value, ttl)
+	w.queue(key, change[V]{value: value})
+}
+
+// Delete deletes the key from the cache, and queues its deletion from the store.
+func (w *WriteBehind[K, V]) Delete(key K) {
+	w.cache.Delete(key)
+	w.queue(key, change[V]{deleted: true})
+}
+
+// Len returns number of items in the cache.
+func (w *WriteBehind[K, V]) Len() int {
+	return w.cache.Len()
+}
+
+// Pending returns number of changes not yet written to the store.
+func (w *WriteBehind[K, V]) Pending() int {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	return len(w.dirty)
+}
+
+// Flush writes all pending changes to the store, returning the first error or the context error.
+// Changes failed to be written stay pending.
+func (w *WriteBehind[K, V]) Flush(ctx context.Context) error {
+	w.fm.Lock()
+	defer w.fm.Unlock()
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		batch := w.batch()
+		if len(batch) == 0 {
+			return nil
+		}
+
+		if err := w.write(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+// Close stops the background flushing. Changes made after Close are only written by Flush.
+func (w *WriteBehind[K, V]) Close() {
+	w.closeOnce.Do(func() {
+		close(w.stop)
+		<-w.done
+	})
+}
+
+// run flushes the changes every interval, or when enough keys are changed. Failed changes are retried next time.
+func (w *WriteBehind[K, V]) run(interval time.Duration) {
+	defer close(w.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-w.stop:
+			return
+		case <-ticker.C:
+		case <-w.kick:
+		}
+
+		_ = w.Flush(context.Background())
+	}
+}
+
+// queue records the change of the key, replacing the previous one.
+func (w *WriteBehind[K, V]) queue(key K, ch change[V]) {
+	w.m.Lock()
+
+	w.seq++
+	ch.seq = w.seq
+	w.dirty[key] = ch
+	full := len(w.dirty) >= w.batchSize
+
+	w.m.Unlock()
+
+	if full {
+		select {
+		case w.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// batch returns up to batchSize pending changes. They stay pending until acknowledged,
+// so Get does not load older values from the store while they are being written.
+func (w *WriteBehind[K, V]) batch() map[K]change[V] {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	batch := make(map[K]change[V], w.batchSize)
+
+	for key, ch := range w.dirty {
+		if len(batch) == w.batchSize {
+			break
+		}
+
+		batch[key] = ch
+	}
+
+	return batch
+}
+
+// ack removes the written changes, unless their keys have been changed again meanwhile.
+func (w *WriteBehind[K, V]) ack(batch map[K]change[V]) {
+	w.m.Lock()
+	defer w.m.Unlock()
+
+	for key, ch := range batch {
+		if w.dirty[key].seq == ch.seq {
+			delete(w.dirty, key)
+		}
+	}
+}
+
+// write writes the batch to the store, at once if the store supports it.
+func (w *WriteBehind[K, V]) write(ctx context.Context, batch map[K]change[V]) error {
+	if bs, ok := w.store.(BatchStore[K, V]); ok {
+		values := make(map[K]V, len(batch))
+		deleted := make([]K, 0, len(batch))
+
+		for key, ch := range batch {
+			if ch.deleted {
+				deleted = append(deleted, key)
+			} else {
+				values[key] = ch.value
+			}
+		}
+
+		if len(values) > 0 {
+			if err := bs.SetMany(ctx, values); err != nil {
+				return err
+			}
+		}
+
+		if len(deleted) > 0 {
+			if err := bs.DeleteMany(ctx, deleted); err != nil {
+				return err
+			}
+		}
+
+		w.ack(batch)
+
+		return nil
+	}
+
+	done := make(map[K]change[V], len(batch))
+	defer w.ack(done)
+
+	for key, ch := range batch {
+		var err error
+
+		if ch.deleted {
+			err = w.store.Delete(ctx, key)
+		} else {
+			err = w.store.Set(ctx, key, ch.value)
+		}
+
+		if err != nil {
+			return err
+		}
+
+		done[key] = ch
+	}
+
+	return nil
+}